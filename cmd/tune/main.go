@@ -0,0 +1,86 @@
+package main
+
+import (
+	"VendingMachineWeightRecognition/pkg/tuning"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func main() {
+	datasetPath := flag.String("dataset", "", "标注数据集 JSON 文件")
+	sensor := flag.String("sensor", "0,5,10,15,20", "传感器容差候选值，单位 g，逗号分隔")
+	pkg := flag.String("package", "0,2,5,10", "包装容差候选值，百分比，逗号分隔")
+	flag.Parse()
+
+	if *datasetPath == "" {
+		log.Fatal("必须指定 -dataset")
+	}
+
+	data, err := os.ReadFile(*datasetPath)
+	if err != nil {
+		log.Fatalf("读取数据集失败: %v", err)
+	}
+	var dataset tuning.Dataset
+	if err := json.Unmarshal(data, &dataset); err != nil {
+		log.Fatalf("解析数据集失败: %v", err)
+	}
+
+	grid := tuning.Grid{}
+	for _, field := range strings.Split(*sensor, ",") {
+		value, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			log.Fatalf("传感器容差无效: %v", err)
+		}
+		grid.SensorTolerances = append(grid.SensorTolerances, value)
+	}
+	for _, field := range strings.Split(*pkg, ",") {
+		value, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			log.Fatalf("包装容差无效: %v", err)
+		}
+		grid.PackageTolerances = append(grid.PackageTolerances, value)
+	}
+
+	global, layers, err := tuning.Sweep(dataset, grid)
+	if err != nil {
+		log.Fatalf("容差网格无效: %v", err)
+	}
+
+	fmt.Println("整机指标:")
+	printMetrics(global)
+	fmt.Println("分层指标:")
+	printMetrics(layers)
+
+	if best, ok := tuning.Best(global); ok {
+		fmt.Printf("推荐整机参数: 传感器容差 %dg, 包装容差 %.2f%%\n", best.SensorTolerance, best.PackageTolerance)
+	}
+	perLayer := tuning.BestPerLayer(layers)
+	indexes := make([]int, 0, len(perLayer))
+	for layer := range perLayer {
+		indexes = append(indexes, layer)
+	}
+	sort.Ints(indexes)
+	for _, layer := range indexes {
+		best := perLayer[layer]
+		fmt.Printf("推荐第%d层参数: 传感器容差 %dg, 包装容差 %.2f%%\n", layer, best.SensorTolerance, best.PackageTolerance)
+	}
+}
+
+// printMetrics 以表格形式输出评估结果
+func printMetrics(metrics []tuning.Metrics) {
+	fmt.Printf("%-6s %-10s %-10s %-8s %-10s %-10s %-10s\n", "层", "传感器容差", "包装容差", "会话数", "准确率", "误收费率", "异常率")
+	for _, m := range metrics {
+		layer := "整机"
+		if m.Layer > 0 {
+			layer = strconv.Itoa(m.Layer)
+		}
+		fmt.Printf("%-6s %-10d %-10.2f %-8d %-10.3f %-10.3f %-10.3f\n",
+			layer, m.SensorTolerance, m.PackageTolerance, m.Sessions, m.Accuracy, m.FalseChargeRate, m.ExceptionRate)
+	}
+}
@@ -1,8 +1,9 @@
 package main
 
 import (
+	"VendingMachineWeightRecognition/pkg/config"
 	"VendingMachineWeightRecognition/pkg/model"
-	"VendingMachineWeightRecognition/pkg/recognition"
+	"flag"
 	"fmt"
 	"log"
 )
@@ -10,27 +11,20 @@ import (
 func main() {
 	log.Println("程序启动...")
 
-	// 初始化商品数据
-	goods := []model.Goods{
-		{ID: "1", Weight: 500},
-		{ID: "2", Weight: 500},
-		{ID: "3", Weight: 550},
-	}
+	configPath := flag.String("config", "configs/machine.yaml", "售货机配置文件，支持 JSON 和 YAML")
+	flag.Parse()
 
-	// 初始化库存数据
-	stocks := []model.Stock{
-		{Layer: 1, GoodsID: "1", Num: 5},
-		{Layer: 1, GoodsID: "2", Num: 5},
-		{Layer: 2, GoodsID: "3", Num: 5},
+	// 加载商品目录、货道规划和识别器参数
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("加载配置失败: %v", err)
 	}
 
 	// 创建重量识别器
-	recognizer := recognition.NewWeightRecognizer(
-		10,   // 传感器容差
-		0.05, // 包装容差
-		goods,
-		stocks,
-	)
+	recognizer, err := cfg.NewRecognizer()
+	if err != nil {
+		log.Fatalf("创建识别器失败: %v", err)
+	}
 
 	// 模拟层重量变化
 	beginLayers := []model.Layer{
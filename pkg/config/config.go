@@ -0,0 +1,667 @@
+package config
+
+import (
+	"VendingMachineWeightRecognition/pkg/model"
+	"VendingMachineWeightRecognition/pkg/recognition"
+	"VendingMachineWeightRecognition/pkg/sensor"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format 配置文件格式
+type Format string
+
+const (
+	JSON Format = "json"
+	YAML Format = "yaml"
+)
+
+// Config 售货机配置：商品目录、货道规划、识别器参数和读数滤波
+type Config struct {
+	Layers     int        `json:"layers" yaml:"layers"` // 货柜层数，层号从 1 开始
+	Goods      []Goods    `json:"goods" yaml:"goods"`
+	Stocks     []Stock    `json:"stocks" yaml:"stocks"`
+	Recognizer Recognizer `json:"recognizer" yaml:"recognizer"`
+	Filters    Filters    `json:"filters,omitempty" yaml:"filters,omitempty"`
+}
+
+// Goods 商品目录中的一个商品
+type Goods struct {
+	ID            string      `json:"id" yaml:"id"`                                             // 6 位数字的商品编号
+	Weight        int         `json:"weight" yaml:"weight"`                                     // 单件重量，单位 g
+	PreciseWeight float64     `json:"precise_weight,omitempty" yaml:"precise_weight,omitempty"` // 精确的单件重量，单位 g，非零时优先于 Weight
+	Kind          string      `json:"kind,omitempty" yaml:"kind,omitempty"`                     // unit（默认）或 weighed
+	MinPortion    int         `json:"min_portion,omitempty" yaml:"min_portion,omitempty"`       // 称重商品单次取走的最小重量，单位 g
+	MaxPortion    int         `json:"max_portion,omitempty" yaml:"max_portion,omitempty"`       // 称重商品单次取走的最大重量，单位 g
+	EmptyWeight   int         `json:"empty_weight,omitempty" yaml:"empty_weight,omitempty"`     // 空容器重量，单位 g
+	Components    []Component `json:"components,omitempty" yaml:"components,omitempty"`         // 组合装包含的商品
+	Revisions     []Revision  `json:"revisions,omitempty" yaml:"revisions,omitempty"`           // 更换包装后的重量版本
+
+	Names    map[string]string `json:"names,omitempty" yaml:"names,omitempty"`       // 语言代码到商品名称的映射
+	Barcode  string            `json:"barcode,omitempty" yaml:"barcode,omitempty"`   // 商品条码（GTIN-8/12/13/14）
+	Price    int               `json:"price,omitempty" yaml:"price,omitempty"`       // 单价，单位分，称重商品为每千克的价格
+	Category string            `json:"category,omitempty" yaml:"category,omitempty"` // 商品分类
+	Flags    []string          `json:"flags,omitempty" yaml:"flags,omitempty"`       // 商品标记：age_restricted、refrigerated、fragile
+}
+
+// goodsFlags 配置文件中的商品标记名称
+var goodsFlags = map[string]model.GoodsFlag{
+	"age_restricted": model.AgeRestricted,
+	"refrigerated":   model.Refrigerated,
+	"fragile":        model.Fragile,
+}
+
+// Revision 商品重量的一个版本，从生效时间起代替更早的重量
+type Revision struct {
+	EffectiveFrom time.Time `json:"effective_from" yaml:"effective_from"`                     // 生效时间，RFC 3339 格式
+	Weight        int       `json:"weight" yaml:"weight"`                                     // 单件重量，单位 g
+	PreciseWeight float64   `json:"precise_weight,omitempty" yaml:"precise_weight,omitempty"` // 精确的单件重量，单位 g
+}
+
+// Component 组合装中包含的商品
+type Component struct {
+	GoodsID string `json:"goods_id" yaml:"goods_id"`
+	Num     int    `json:"num" yaml:"num"`
+}
+
+// Stock 货道规划中某层的一个商品及其库存
+type Stock struct {
+	GoodsID  string   `json:"goods_id" yaml:"goods_id"`
+	Layer    int      `json:"layer" yaml:"layer"`
+	Num      int      `json:"num" yaml:"num"`                               // 库存数量，称重商品为库存重量，单位 g
+	Position *float64 `json:"position,omitempty" yaml:"position,omitempty"` // 货道在层上的横向位置，0 为最左端，1 为最右端，多传感器层用于定位取货位置
+}
+
+// Tolerance 包装容差，绝对值和百分比同时生效
+type Tolerance struct {
+	Grams   int     `json:"grams,omitempty" yaml:"grams,omitempty"`
+	Percent float64 `json:"percent,omitempty" yaml:"percent,omitempty"`
+}
+
+// Recognizer 识别器参数
+type Recognizer struct {
+	ResolutionMg     int                  `json:"resolution_mg,omitempty" yaml:"resolution_mg,omitempty"`   // 识别精度，单位 mg，默认 1g
+	SensorTolerance  int                  `json:"sensor_tolerance" yaml:"sensor_tolerance"`                 // 传感器容差，单位 g
+	PreciseSensor    float64              `json:"precise_sensor,omitempty" yaml:"precise_sensor,omitempty"` // 克以下精度的传感器容差，单位 g，如 0.2，非零时优先于 SensorTolerance
+	PackageTolerance Tolerance            `json:"package_tolerance" yaml:"package_tolerance"`
+	Consumption      string               `json:"consumption,omitempty" yaml:"consumption,omitempty"`             // none（默认）、full 或 proportional
+	CabinetTolerance int                  `json:"cabinet_tolerance,omitempty" yaml:"cabinet_tolerance,omitempty"` // 整机重量传感器容差，单位 g
+	SwapWindow       string               `json:"swap_window,omitempty" yaml:"swap_window,omitempty"`             // 调包检测时间窗口，如 30s，空表示不检测
+	AutoSensor       *AutoSensor          `json:"auto_sensor,omitempty" yaml:"auto_sensor,omitempty"`             // 根据空闲读数噪声自动确定传感器容差，不填表示使用固定容差
+	Bump             *Bump                `json:"bump,omitempty" yaml:"bump,omitempty"`                           // 碰撞检测参数，不填表示不检测
+	LaneRadius       float64              `json:"lane_radius,omitempty" yaml:"lane_radius,omitempty"`             // 货道定位半径，库存填写了位置时生效
+	LayerSensor      map[int]float64      `json:"layer_sensor,omitempty" yaml:"layer_sensor,omitempty"`           // 层号到传感器容差的映射，单位 g，可以为小数
+	LayerPackage     map[int]Tolerance    `json:"layer_package,omitempty" yaml:"layer_package,omitempty"`         // 层号到包装容差的映射
+	GoodsPackage     map[string]Tolerance `json:"goods_package,omitempty" yaml:"goods_package,omitempty"`         // 商品编号到包装容差的映射
+}
+
+// AutoSensor 自动传感器容差参数，容差单位 g
+type AutoSensor struct {
+	K          float64 `json:"k" yaml:"k"`                         // 容差为噪声标准差的倍数，例如 3
+	MinSamples int     `json:"min_samples" yaml:"min_samples"`     // 估计噪声所需的最少空闲读数差个数
+	Min        float64 `json:"min,omitempty" yaml:"min,omitempty"` // 容差下限
+	Max        float64 `json:"max,omitempty" yaml:"max,omitempty"` // 容差上限，0 表示只受量程限制
+}
+
+// Bump 碰撞检测参数，未填写的项使用默认值
+type Bump struct {
+	Threshold   float64 `json:"threshold,omitempty" yaml:"threshold,omitempty"`       // 扰动阈值，单位 g，默认 20
+	MaxDuration string  `json:"max_duration,omitempty" yaml:"max_duration,omitempty"` // 瞬时扰动的最长时间，如 500ms
+	MinLayers   int     `json:"min_layers,omitempty" yaml:"min_layers,omitempty"`     // 视为整机碰撞的最少层数，默认 2
+	Guard       string  `json:"guard,omitempty" yaml:"guard,omitempty"`               // 碰撞窗口前后的保护时间，如 200ms
+}
+
+// Filters 各层读数的滤波流水线，未单独配置的层使用 Default
+type Filters struct {
+	Default []Filter         `json:"default,omitempty" yaml:"default,omitempty"`
+	Layers  map[int][]Filter `json:"layers,omitempty" yaml:"layers,omitempty"`
+}
+
+// Filter 单个滤波器的配置
+type Filter struct {
+	Kind             string  `json:"kind" yaml:"kind"`                                               // moving_average、median、outlier 或 kalman
+	Window           int     `json:"window,omitempty" yaml:"window,omitempty"`                       // 滑动平均、中值和野值剔除的窗口长度
+	Threshold        float64 `json:"threshold,omitempty" yaml:"threshold,omitempty"`                 // 野值剔除阈值，单位 g
+	ProcessNoise     float64 `json:"process_noise,omitempty" yaml:"process_noise,omitempty"`         // 卡尔曼滤波的过程噪声方差
+	MeasurementNoise float64 `json:"measurement_noise,omitempty" yaml:"measurement_noise,omitempty"` // 卡尔曼滤波的测量噪声方差
+}
+
+// Load 读取并校验配置文件，按扩展名选择格式，.yaml 和 .yml 为 YAML，其余为 JSON
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %w", err)
+	}
+
+	cfg, err := Parse(data, formatOf(path))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// formatOf 按扩展名判断配置文件格式
+func formatOf(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return YAML
+	default:
+		return JSON
+	}
+}
+
+// Parse 解析并校验配置内容，未知字段视为错误，避免拼写错误的配置项被静默忽略
+func Parse(data []byte, format Format) (*Config, error) {
+	cfg := &Config{}
+	switch format {
+	case JSON:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(cfg); err != nil {
+			return nil, fmt.Errorf("解析 JSON 配置失败: %w", err)
+		}
+	case YAML:
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(cfg); err != nil {
+			return nil, fmt.Errorf("解析 YAML 配置失败: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("未知的配置文件格式 %q", format)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate 检查配置是否合理，返回所有问题
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Layers < 1 {
+		errs = append(errs, fmt.Errorf("货柜层数至少为 1，实际为 %d", c.Layers))
+	}
+
+	kinds := make(map[string]model.GoodsKind, len(c.Goods))
+	for i, good := range c.Goods {
+		if !validID(good.ID) {
+			errs = append(errs, fmt.Errorf("第%d个商品的编号 %q 不是 6 位数字", i+1, good.ID))
+		} else if _, exists := kinds[good.ID]; exists {
+			errs = append(errs, fmt.Errorf("商品 %s 重复", good.ID))
+		}
+		kind, err := parseKind(good.Kind)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("商品 %s: %w", good.ID, err))
+		}
+		kinds[good.ID] = kind
+
+		if good.Weight < 0 || good.PreciseWeight < 0 || good.MinPortion < 0 || good.MaxPortion < 0 || good.EmptyWeight < 0 {
+			errs = append(errs, fmt.Errorf("商品 %s 的重量不能为负数", good.ID))
+		}
+		switch {
+		case kind == model.WeighedGoods:
+			if good.MaxPortion > 0 && good.MinPortion > good.MaxPortion {
+				errs = append(errs, fmt.Errorf("商品 %s 的最小取货重量 %dg 大于最大取货重量 %dg", good.ID, good.MinPortion, good.MaxPortion))
+			}
+		case len(good.Components) > 0:
+			// 组合装可以不填写重量，按所含商品计算
+		case good.Weight == 0 && good.PreciseWeight == 0:
+			errs = append(errs, fmt.Errorf("商品 %s 的单件重量必须为正数", good.ID))
+		}
+		if unit := unitWeight(good.Weight, good.PreciseWeight); good.EmptyWeight > 0 && model.Grams(good.EmptyWeight) >= unit {
+			errs = append(errs, fmt.Errorf("商品 %s 的空容器重量 %dg 不小于单件重量 %v", good.ID, good.EmptyWeight, unit))
+		}
+
+		if good.Barcode != "" && !validGTIN(good.Barcode) {
+			errs = append(errs, fmt.Errorf("商品 %s 的条码 %q 无效", good.ID, good.Barcode))
+		}
+		if good.Price < 0 {
+			errs = append(errs, fmt.Errorf("商品 %s 的价格不能为负数", good.ID))
+		}
+		for _, flag := range good.Flags {
+			if _, ok := goodsFlags[flag]; !ok {
+				errs = append(errs, fmt.Errorf("商品 %s 的标记 %q 未知", good.ID, flag))
+			}
+		}
+
+		effective := make(map[time.Time]bool, len(good.Revisions))
+		for _, revision := range good.Revisions {
+			if revision.EffectiveFrom.IsZero() {
+				errs = append(errs, fmt.Errorf("商品 %s 的重量版本缺少生效时间", good.ID))
+			} else if effective[revision.EffectiveFrom.UTC()] {
+				errs = append(errs, fmt.Errorf("商品 %s 在 %s 生效的重量版本重复", good.ID, revision.EffectiveFrom.Format(time.RFC3339)))
+			}
+			effective[revision.EffectiveFrom.UTC()] = true
+			if kind == model.UnitGoods && (revision.Weight < 0 || revision.PreciseWeight < 0 || revision.Weight == 0 && revision.PreciseWeight == 0) {
+				errs = append(errs, fmt.Errorf("商品 %s 在 %s 生效的单件重量必须为正数", good.ID, revision.EffectiveFrom.Format(time.RFC3339)))
+			} else if unit := unitWeight(revision.Weight, revision.PreciseWeight); good.EmptyWeight > 0 && model.Grams(good.EmptyWeight) >= unit {
+				errs = append(errs, fmt.Errorf("商品 %s 的空容器重量 %dg 不小于 %s 生效的单件重量 %v", good.ID, good.EmptyWeight, revision.EffectiveFrom.Format(time.RFC3339), unit))
+			}
+		}
+	}
+
+	// 组合装所含商品必须是目录中的按件商品，可以是其他组合装但不能循环包含
+	components := make(map[string][]Component, len(c.Goods))
+	for _, good := range c.Goods {
+		components[good.ID] = good.Components
+	}
+	for _, good := range c.Goods {
+		if len(good.Components) > 0 && containsBundle(components, good.ID, good.ID, make(map[string]bool)) {
+			errs = append(errs, fmt.Errorf("组合装 %s 循环包含自身", good.ID))
+		}
+		for _, component := range good.Components {
+			kind, exists := kinds[component.GoodsID]
+			switch {
+			case !exists:
+				errs = append(errs, fmt.Errorf("组合装 %s 包含未知商品 %s", good.ID, component.GoodsID))
+			case kind != model.UnitGoods:
+				errs = append(errs, fmt.Errorf("组合装 %s 不能包含商品 %s", good.ID, component.GoodsID))
+			}
+			if component.Num < 1 {
+				errs = append(errs, fmt.Errorf("组合装 %s 中商品 %s 的数量必须为正数", good.ID, component.GoodsID))
+			}
+		}
+	}
+
+	type slot struct {
+		layer   int
+		goodsID string
+	}
+	seen := make(map[slot]bool, len(c.Stocks))
+	for _, stock := range c.Stocks {
+		if _, exists := kinds[stock.GoodsID]; !exists {
+			errs = append(errs, fmt.Errorf("第%d层的库存引用了未知商品 %s", stock.Layer, stock.GoodsID))
+		}
+		if stock.Layer < 1 || stock.Layer > c.Layers {
+			errs = append(errs, fmt.Errorf("商品 %s 的层号 %d 超出范围 1-%d", stock.GoodsID, stock.Layer, c.Layers))
+		}
+		if stock.Num < 0 {
+			errs = append(errs, fmt.Errorf("第%d层商品 %s 的库存不能为负数", stock.Layer, stock.GoodsID))
+		}
+		if p := stock.Position; p != nil && (math.IsNaN(*p) || *p < 0 || *p > 1) {
+			errs = append(errs, fmt.Errorf("第%d层商品 %s 的货道位置必须在 [0, 1] 范围内，实际为 %v", stock.Layer, stock.GoodsID, *p))
+		}
+		key := slot{stock.Layer, stock.GoodsID}
+		if seen[key] {
+			errs = append(errs, fmt.Errorf("第%d层商品 %s 的库存重复", stock.Layer, stock.GoodsID))
+		}
+		seen[key] = true
+	}
+
+	for layer := range c.Recognizer.LayerSensor {
+		if layer < 1 || layer > c.Layers {
+			errs = append(errs, fmt.Errorf("传感器容差覆盖的层号 %d 超出范围 1-%d", layer, c.Layers))
+		}
+	}
+	for layer := range c.Recognizer.LayerPackage {
+		if layer < 1 || layer > c.Layers {
+			errs = append(errs, fmt.Errorf("包装容差覆盖的层号 %d 超出范围 1-%d", layer, c.Layers))
+		}
+	}
+	for goodsID := range c.Recognizer.GoodsPackage {
+		if _, exists := kinds[goodsID]; !exists {
+			errs = append(errs, fmt.Errorf("包装容差覆盖引用了未知商品 %s", goodsID))
+		}
+	}
+
+	for layer := range c.Filters.Layers {
+		if layer < 1 || layer > c.Layers {
+			errs = append(errs, fmt.Errorf("滤波器配置的层号 %d 超出范围 1-%d", layer, c.Layers))
+		}
+	}
+
+	r := c.Recognizer
+	if r.ResolutionMg < 0 || r.SensorTolerance < 0 || r.CabinetTolerance < 0 {
+		errs = append(errs, errors.New("识别精度和传感器容差不能为负数"))
+	}
+	if !(r.PreciseSensor >= 0 && r.PreciseSensor <= sensor.MaxWeight) {
+		errs = append(errs, fmt.Errorf("精确传感器容差必须在 [0, %dg] 范围内，实际为 %vg", sensor.MaxWeight, r.PreciseSensor))
+	}
+	if !(r.LaneRadius >= 0) {
+		errs = append(errs, fmt.Errorf("货道定位半径不能为负数，实际为 %v", r.LaneRadius))
+	}
+	if err := r.PackageTolerance.tolerance().Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("包装容差: %w", err))
+	}
+	if options, err := c.Options(); err != nil {
+		errs = append(errs, err)
+	} else {
+		if options.SwapWindow < 0 {
+			errs = append(errs, fmt.Errorf("调包检测时间窗口不能为负数，实际为 %v", options.SwapWindow))
+		}
+		if options.AutoSensor != nil {
+			if err := options.AutoSensor.Validate(); err != nil {
+				errs = append(errs, fmt.Errorf("自动传感器容差: %w", err))
+			}
+		}
+		if options.Bump != nil {
+			if err := options.Bump.Validate(); err != nil {
+				errs = append(errs, fmt.Errorf("碰撞检测: %w", err))
+			}
+		}
+	}
+	if err := c.overrides().Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("容差覆盖: %w", err))
+	}
+	if err := c.Pipeline().Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("滤波器: %w", err))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Catalog 返回配置中的商品目录和库存
+func (c *Config) Catalog() ([]model.Goods, []model.Stock) {
+	goods := make([]model.Goods, 0, len(c.Goods))
+	for _, g := range c.Goods {
+		kind, _ := parseKind(g.Kind)
+		good := model.Goods{
+			ID:          g.ID,
+			Weight:      g.Weight,
+			Precise:     model.GramsFloat(g.PreciseWeight),
+			Kind:        kind,
+			MinPortion:  g.MinPortion,
+			MaxPortion:  g.MaxPortion,
+			EmptyWeight: g.EmptyWeight,
+			Info: model.GoodsInfo{
+				Names:    g.Names,
+				Barcode:  g.Barcode,
+				Price:    g.Price,
+				Category: g.Category,
+			},
+		}
+		for _, flag := range g.Flags {
+			good.Info.Flags |= goodsFlags[flag]
+		}
+		for _, component := range g.Components {
+			good.Components = append(good.Components, model.BundleComponent{GoodsID: component.GoodsID, Num: component.Num})
+		}
+		for _, revision := range g.Revisions {
+			good.Revisions = append(good.Revisions, model.GoodsRevision{
+				EffectiveFrom: revision.EffectiveFrom,
+				Weight:        revision.Weight,
+				Precise:       model.GramsFloat(revision.PreciseWeight),
+			})
+		}
+		goods = append(goods, good)
+	}
+
+	stocks := make([]model.Stock, 0, len(c.Stocks))
+	for _, s := range c.Stocks {
+		stocks = append(stocks, model.Stock{GoodsID: s.GoodsID, Layer: s.Layer, Num: s.Num})
+	}
+
+	return goods, stocks
+}
+
+// Options 返回配置对应的识别器创建参数，包含商品目录和库存
+func (c *Config) Options() (recognition.Options, error) {
+	r := c.Recognizer
+	consumption, err := parseConsumption(r.Consumption)
+	if err != nil {
+		return recognition.Options{}, err
+	}
+	var swapWindow time.Duration
+	if r.SwapWindow != "" {
+		if swapWindow, err = time.ParseDuration(r.SwapWindow); err != nil {
+			return recognition.Options{}, fmt.Errorf("调包检测时间窗口无效: %w", err)
+		}
+	}
+	bump, err := r.Bump.config()
+	if err != nil {
+		return recognition.Options{}, err
+	}
+
+	goods, stocks := c.Catalog()
+	return recognition.Options{
+		Resolution:       model.Weight(r.ResolutionMg) * model.Milligram,
+		SensorTolerance:  r.SensorTolerance,
+		PreciseSensor:    model.GramsFloat(r.PreciseSensor),
+		PackageTolerance: r.PackageTolerance.tolerance(),
+		Consumption:      consumption,
+		CabinetTolerance: r.CabinetTolerance,
+		SwapWindow:       swapWindow,
+		Bump:             bump,
+		AutoSensor:       r.AutoSensor.config(),
+		Goods:            goods,
+		Stocks:           stocks,
+	}, nil
+}
+
+// Pipeline 返回配置中的滤波流水线
+func (c *Config) Pipeline() sensor.PipelineConfig {
+	pipeline := sensor.PipelineConfig{Default: filterConfigs(c.Filters.Default)}
+	if len(c.Filters.Layers) > 0 {
+		pipeline.Layers = make(map[int][]sensor.FilterConfig, len(c.Filters.Layers))
+		for layer, filters := range c.Filters.Layers {
+			pipeline.Layers[layer] = filterConfigs(filters)
+		}
+	}
+	return pipeline
+}
+
+// NewFilters 根据配置创建各层读数的滤波流水线
+func (c *Config) NewFilters() (*sensor.LayerFilters, error) {
+	return sensor.NewLayerFilters(c.Pipeline())
+}
+
+// NewRecognizer 根据配置创建识别器并设置容差覆盖
+func (c *Config) NewRecognizer() (*recognition.WeightRecognizer, error) {
+	options, err := c.Options()
+	if err != nil {
+		return nil, err
+	}
+	recognizer, err := recognition.NewWeightRecognizerWithOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	if err := recognizer.SetToleranceOverrides(c.overrides()); err != nil {
+		return nil, err
+	}
+	if err := recognizer.SetPlanogram(c.planogram()); err != nil {
+		return nil, err
+	}
+	return recognizer, nil
+}
+
+// planogram 返回库存中填写的货道位置和定位半径
+func (c *Config) planogram() (map[int]map[string]float64, float64) {
+	positions := make(map[int]map[string]float64)
+	for _, stock := range c.Stocks {
+		if stock.Position == nil {
+			continue
+		}
+		if positions[stock.Layer] == nil {
+			positions[stock.Layer] = make(map[string]float64)
+		}
+		positions[stock.Layer][stock.GoodsID] = *stock.Position
+	}
+	return positions, c.Recognizer.LaneRadius
+}
+
+// overrides 返回配置中的容差覆盖
+func (c *Config) overrides() recognition.ToleranceOverrides {
+	r := c.Recognizer
+	overrides := recognition.ToleranceOverrides{}
+	if len(r.LayerSensor) > 0 {
+		overrides.LayerSensor = make(map[int]model.Weight, len(r.LayerSensor))
+		for layer, tolerance := range r.LayerSensor {
+			overrides.LayerSensor[layer] = model.GramsFloat(tolerance)
+		}
+	}
+	if len(r.LayerPackage) > 0 {
+		overrides.LayerPackage = make(map[int]recognition.Tolerance, len(r.LayerPackage))
+		for layer, tolerance := range r.LayerPackage {
+			overrides.LayerPackage[layer] = tolerance.tolerance()
+		}
+	}
+	if len(r.GoodsPackage) > 0 {
+		overrides.GoodsPackage = make(map[string]recognition.Tolerance, len(r.GoodsPackage))
+		for goodsID, tolerance := range r.GoodsPackage {
+			overrides.GoodsPackage[goodsID] = tolerance.tolerance()
+		}
+	}
+	return overrides
+}
+
+// config 转换为识别器的自动容差参数，未配置时返回 nil
+func (a *AutoSensor) config() *recognition.AutoSensorTolerance {
+	if a == nil {
+		return nil
+	}
+	return &recognition.AutoSensorTolerance{
+		K:          a.K,
+		MinSamples: a.MinSamples,
+		Min:        model.GramsFloat(a.Min),
+		Max:        model.GramsFloat(a.Max),
+	}
+}
+
+// config 在默认值的基础上转换为碰撞检测参数，未配置时返回 nil
+func (b *Bump) config() (*sensor.BumpConfig, error) {
+	if b == nil {
+		return nil, nil
+	}
+	bump := sensor.DefaultBumpConfig()
+	if b.Threshold != 0 {
+		bump.Threshold = model.GramsFloat(b.Threshold)
+	}
+	if b.MinLayers != 0 {
+		bump.MinLayers = b.MinLayers
+	}
+	var err error
+	if b.MaxDuration != "" {
+		if bump.MaxDuration, err = time.ParseDuration(b.MaxDuration); err != nil {
+			return nil, fmt.Errorf("碰撞检测的瞬时扰动时长无效: %w", err)
+		}
+	}
+	if b.Guard != "" {
+		if bump.Guard, err = time.ParseDuration(b.Guard); err != nil {
+			return nil, fmt.Errorf("碰撞检测的保护时间无效: %w", err)
+		}
+	}
+	return &bump, nil
+}
+
+// filterConfigs 转换为滤波器配置
+func filterConfigs(filters []Filter) []sensor.FilterConfig {
+	if len(filters) == 0 {
+		return nil
+	}
+	configs := make([]sensor.FilterConfig, len(filters))
+	for i, f := range filters {
+		configs[i] = sensor.FilterConfig{
+			Kind:             sensor.FilterKind(f.Kind),
+			Window:           f.Window,
+			Threshold:        f.Threshold,
+			ProcessNoise:     f.ProcessNoise,
+			MeasurementNoise: f.MeasurementNoise,
+		}
+	}
+	return configs
+}
+
+// unitWeight 返回配置中的单件重量，精确重量非零时优先
+func unitWeight(weight int, precise float64) model.Weight {
+	if precise != 0 {
+		return model.GramsFloat(precise)
+	}
+	return model.Grams(weight)
+}
+
+// tolerance 转换为识别器的包装容差
+func (t Tolerance) tolerance() recognition.Tolerance {
+	return recognition.CombinedTolerance(t.Grams, t.Percent)
+}
+
+// containsBundle 判断组合装 id 是否直接或间接包含 target，visited 记录已检查的组合装
+func containsBundle(components map[string][]Component, id string, target string, visited map[string]bool) bool {
+	for _, component := range components[id] {
+		if component.GoodsID == target {
+			return true
+		}
+		if visited[component.GoodsID] {
+			continue
+		}
+		visited[component.GoodsID] = true
+		if containsBundle(components, component.GoodsID, target, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// validID 判断商品编号是否为 6 位数字
+func validID(id string) bool {
+	if len(id) != 6 {
+		return false
+	}
+	for _, c := range id {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// validGTIN 判断条码是否为校验位正确的 GTIN-8、GTIN-12、GTIN-13 或 GTIN-14
+func validGTIN(barcode string) bool {
+	switch len(barcode) {
+	case 8, 12, 13, 14:
+	default:
+		return false
+	}
+
+	// 从右往左，校验位之前的数字交替乘以 3 和 1
+	sum := 0
+	for i := len(barcode) - 1; i >= 0; i-- {
+		c := barcode[i]
+		if c < '0' || c > '9' {
+			return false
+		}
+		digit := int(c - '0')
+		if i == len(barcode)-1 {
+			continue
+		}
+		if (len(barcode)-1-i)%2 == 1 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	return (10-sum%10)%10 == int(barcode[len(barcode)-1]-'0')
+}
+
+// parseKind 解析商品的计量方式，空表示按件销售
+func parseKind(kind string) (model.GoodsKind, error) {
+	switch kind {
+	case "", "unit":
+		return model.UnitGoods, nil
+	case "weighed":
+		return model.WeighedGoods, nil
+	default:
+		return model.UnitGoods, fmt.Errorf("未知的计量方式 %q", kind)
+	}
+}
+
+// parseConsumption 解析部分饮用后放回的计费策略，空表示不计费
+func parseConsumption(policy string) (recognition.ConsumptionPolicy, error) {
+	for _, p := range []recognition.ConsumptionPolicy{recognition.ChargeNone, recognition.ChargeFull, recognition.ChargeProportional} {
+		if policy == p.String() {
+			return p, nil
+		}
+	}
+	if policy == "" {
+		return recognition.ChargeNone, nil
+	}
+	return recognition.ChargeNone, fmt.Errorf("未知的部分饮用计费策略 %q", policy)
+}
@@ -0,0 +1,297 @@
+package config
+
+import (
+	"VendingMachineWeightRecognition/pkg/model"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const machineYAML = `
+layers: 2
+goods:
+  - id: "000001"
+    weight: 480
+    revisions:
+      - {effective_from: 2020-01-01T00:00:00+08:00, weight: 500}
+  - id: "000002"
+    weight: 300
+    empty_weight: 20
+    names: {zh-CN: 矿泉水, en: Water}
+    barcode: "4006381333931"
+    price: 200
+    category: 饮料
+    flags: [refrigerated]
+  - id: "000003"
+    weight: 1050
+    components:
+      - {goods_id: "000001", num: 2}
+  - id: "000004"
+    kind: weighed
+    min_portion: 50
+    max_portion: 500
+stocks:
+  - {goods_id: "000001", layer: 1, num: 5}
+  - {goods_id: "000003", layer: 1, num: 2}
+  - {goods_id: "000002", layer: 2, num: 4}
+  - {goods_id: "000004", layer: 2, num: 2000}
+recognizer:
+  sensor_tolerance: 5
+  package_tolerance: {percent: 2}
+  consumption: proportional
+  swap_window: 30s
+  layer_sensor: {2: 8}
+  goods_package: {"000002": {grams: 3}}
+`
+
+// TestLoad 测试从 YAML 和 JSON 文件加载配置并创建识别器
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "machine.yml")
+	jsonPath := filepath.Join(dir, "machine.json")
+	if err := os.WriteFile(yamlPath, []byte(machineYAML), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	json := `{"layers": 1, "goods": [{"id": "000001", "weight": 500}], "stocks": [{"goods_id": "000001", "layer": 1, "num": 3}],
+		"recognizer": {"sensor_tolerance": 5}}`
+	if err := os.WriteFile(jsonPath, []byte(json), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(yamlPath)
+	if err != nil {
+		t.Fatalf("加载 YAML 配置失败: %v", err)
+	}
+	goods, stocks := cfg.Catalog()
+	if len(goods) != 4 || len(stocks) != 4 {
+		t.Fatalf("商品或库存数量错误: %d %d", len(goods), len(stocks))
+	}
+	if len(goods[0].Revisions) != 1 || goods[0].At(time.Now()).Weight != 500 {
+		t.Errorf("重量版本解析错误: %+v", goods[0])
+	}
+	if info := goods[1].Info; info.Name("en") != "Water" || info.Price != 200 || info.Category != "饮料" || !info.Flags.Has(model.Refrigerated) {
+		t.Errorf("商品描述信息解析错误: %+v", info)
+	}
+	if goods[3].Kind != model.WeighedGoods || len(goods[2].Components) != 1 {
+		t.Errorf("称重商品或组合装解析错误: %+v", goods)
+	}
+
+	options, err := cfg.Options()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if options.SwapWindow.Seconds() != 30 || options.Consumption.String() != "proportional" {
+		t.Errorf("识别器参数解析错误: %+v", options)
+	}
+
+	recognizer, err := cfg.NewRecognizer()
+	if err != nil {
+		t.Fatalf("创建识别器失败: %v", err)
+	}
+	// 组合装含50g包装，超出容差可以与2个散装单品区分，取走1个组合装
+	result := recognizer.Recognize(
+		[]model.Layer{{Index: 1, Weight: 4500}},
+		[]model.Layer{{Index: 1, Weight: 3450}},
+	)
+	if !result.Successful || len(result.Items) != 1 || result.Items[0].GoodsID != "000003" {
+		t.Errorf("应该识别出1个组合装，实际结果%+v", result)
+	}
+
+	if cfg, err := Load(jsonPath); err != nil || cfg.Layers != 1 {
+		t.Errorf("加载 JSON 配置失败: %v", err)
+	}
+}
+
+// TestParse_Invalid 测试拒绝不合理的配置
+func TestParse_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   string
+	}{
+		{
+			name:   "编号不是6位数字",
+			config: `{"layers": 1, "goods": [{"id": "1", "weight": 500}]}`,
+			want:   "不是 6 位数字",
+		},
+		{
+			name:   "编号重复",
+			config: `{"layers": 1, "goods": [{"id": "000001", "weight": 500}, {"id": "000001", "weight": 300}]}`,
+			want:   "重复",
+		},
+		{
+			name:   "重量不是正数",
+			config: `{"layers": 1, "goods": [{"id": "000001", "weight": 0}]}`,
+			want:   "必须为正数",
+		},
+		{
+			name:   "重量版本缺少生效时间",
+			config: `{"layers": 1, "goods": [{"id": "000001", "weight": 500, "revisions": [{"weight": 450}]}]}`,
+			want:   "缺少生效时间",
+		},
+		{
+			name:   "条码校验位错误",
+			config: `{"layers": 1, "goods": [{"id": "000001", "weight": 500, "barcode": "4006381333932"}]}`,
+			want:   "条码",
+		},
+		{
+			name:   "未知的商品标记",
+			config: `{"layers": 1, "goods": [{"id": "000001", "weight": 500, "flags": ["frozen"]}]}`,
+			want:   "标记",
+		},
+		{
+			name:   "库存引用未知商品",
+			config: `{"layers": 1, "goods": [{"id": "000001", "weight": 500}], "stocks": [{"goods_id": "000002", "layer": 1, "num": 1}]}`,
+			want:   "未知商品 000002",
+		},
+		{
+			name:   "层号超出范围",
+			config: `{"layers": 2, "goods": [{"id": "000001", "weight": 500}], "stocks": [{"goods_id": "000001", "layer": 3, "num": 1}]}`,
+			want:   "超出范围",
+		},
+		{
+			name:   "组合装包含未知商品",
+			config: `{"layers": 1, "goods": [{"id": "000001", "components": [{"goods_id": "000009", "num": 2}]}]}`,
+			want:   "包含未知商品",
+		},
+		{
+			name:   "组合装循环包含",
+			config: `{"layers": 1, "goods": [{"id": "000001", "components": [{"goods_id": "000002", "num": 2}]}, {"id": "000002", "components": [{"goods_id": "000001", "num": 1}]}]}`,
+			want:   "循环包含",
+		},
+		{
+			name:   "包装容差不合理",
+			config: `{"layers": 1, "recognizer": {"package_tolerance": {"percent": 150}}}`,
+			want:   "包装容差",
+		},
+		{
+			name:   "空容器重量不小于精确重量",
+			config: `{"layers": 1, "goods": [{"id": "000001", "weight": 300, "precise_weight": 19.5, "empty_weight": 20}]}`,
+			want:   "空容器重量",
+		},
+		{
+			name:   "货道位置超出范围",
+			config: `{"layers": 1, "goods": [{"id": "000001", "weight": 500}], "stocks": [{"goods_id": "000001", "layer": 1, "num": 1, "position": 1.5}]}`,
+			want:   "货道位置",
+		},
+		{
+			name:   "精确传感器容差为负数",
+			config: `{"layers": 1, "recognizer": {"precise_sensor": -0.2}}`,
+			want:   "精确传感器容差",
+		},
+		{
+			name:   "自动容差倍数不是正数",
+			config: `{"layers": 1, "recognizer": {"auto_sensor": {"min_samples": 100}}}`,
+			want:   "自动传感器容差",
+		},
+		{
+			name:   "碰撞检测时长无效",
+			config: `{"layers": 1, "recognizer": {"bump": {"max_duration": "half a second"}}}`,
+			want:   "瞬时扰动时长",
+		},
+		{
+			name:   "未知的滤波器类型",
+			config: `{"layers": 1, "filters": {"default": [{"kind": "lowpass"}]}}`,
+			want:   "滤波器",
+		},
+		{
+			name:   "滤波器层号超出范围",
+			config: `{"layers": 1, "filters": {"layers": {"2": [{"kind": "median", "window": 3}]}}}`,
+			want:   "超出范围",
+		},
+		{
+			name:   "未知字段",
+			config: `{"layers": 1, "sensor_tolerance": 5}`,
+			want:   "unknown field",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.config), JSON)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("应该返回包含 %q 的错误，实际为 %v", tt.want, err)
+			}
+		})
+	}
+}
+
+// TestConfig_SensorSections 测试自动容差、碰撞检测、滤波、货道位置和克以下容差的配置
+func TestConfig_SensorSections(t *testing.T) {
+	cfg, err := Parse([]byte(`
+layers: 2
+goods:
+  - {id: "000001", weight: 500}
+  - {id: "000002", weight: 502}
+  - {id: "000003", weight: 3, precise_weight: 3.2}
+stocks:
+  - {goods_id: "000001", layer: 1, num: 10, position: 0.1}
+  - {goods_id: "000002", layer: 1, num: 10, position: 0.9}
+  - {goods_id: "000003", layer: 2, num: 50}
+recognizer:
+  resolution_mg: 100
+  sensor_tolerance: 5
+  precise_sensor: 0.2
+  lane_radius: 0.2
+  layer_sensor: {1: 5, 2: 0.3}
+  auto_sensor: {k: 3, min_samples: 100, min: 0.5, max: 10}
+  bump: {threshold: 15, guard: 100ms}
+filters:
+  default:
+    - {kind: median, window: 3}
+  layers:
+    2:
+      - {kind: kalman, process_noise: 0.01, measurement_noise: 4}
+`), YAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	options, err := cfg.Options()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if options.PreciseSensor != model.GramsFloat(0.2) {
+		t.Errorf("精确传感器容差应该为0.2g，实际为%v", options.PreciseSensor)
+	}
+	if auto := options.AutoSensor; auto == nil || auto.K != 3 || auto.MinSamples != 100 || auto.Min != model.GramsFloat(0.5) || auto.Max != model.Grams(10) {
+		t.Errorf("自动传感器容差解析错误: %+v", auto)
+	}
+	if bump := options.Bump; bump == nil || bump.Threshold != model.Grams(15) || bump.Guard != 100*time.Millisecond || bump.MaxDuration != 500*time.Millisecond || bump.MinLayers != 2 {
+		t.Errorf("碰撞检测参数应该在默认值基础上覆盖，实际为%+v", bump)
+	}
+	if overrides := cfg.overrides(); overrides.LayerSensor[2] != model.GramsFloat(0.3) {
+		t.Errorf("分层传感器容差应该为0.3g，实际为%v", overrides.LayerSensor[2])
+	}
+
+	pipeline := cfg.Pipeline()
+	if len(pipeline.Default) != 1 || pipeline.Default[0].Window != 3 || len(pipeline.Layers[2]) != 1 || pipeline.Layers[2][0].MeasurementNoise != 4 {
+		t.Errorf("滤波流水线解析错误: %+v", pipeline)
+	}
+	if _, err := cfg.NewFilters(); err != nil {
+		t.Errorf("创建滤波流水线失败: %v", err)
+	}
+
+	recognizer, err := cfg.NewRecognizer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 货道位置用于区分重量相近的商品
+	cells := func(left, right int) []model.Layer {
+		return []model.Layer{{Index: 1, Cells: []model.Cell{{Position: 0, Weight: left}, {Position: 1, Weight: right}}}}
+	}
+	result := recognizer.Recognize(cells(3000, 3000), cells(2550, 2950))
+	if len(result.Items) != 1 || result.Items[0].GoodsID != "000001" {
+		t.Errorf("应该根据货道位置识别出左侧的商品1，实际结果%+v", result)
+	}
+	// 克以下的分层容差
+	result = recognizer.Recognize(
+		[]model.Layer{{Index: 2, Precise: model.GramsFloat(200)}},
+		[]model.Layer{{Index: 2, Precise: model.GramsFloat(193.4)}},
+	)
+	if len(result.Items) != 1 || result.Items[0].GoodsID != "000003" || result.Items[0].Num != 2 {
+		t.Errorf("应该识别出2个口香糖，实际结果%+v", result)
+	}
+}
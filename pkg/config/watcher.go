@@ -0,0 +1,144 @@
+package config
+
+import (
+	"VendingMachineWeightRecognition/pkg/recognition"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// ErrRestartRequired 配置中只能在创建识别器时设置的参数发生了变化
+var ErrRestartRequired = errors.New("识别器参数变化需要重启后生效")
+
+// Watcher 轮询配置文件，内容变化且校验通过时将新的商品目录、货道规划和容差覆盖原子地切换到识别器
+// 正在进行的识别继续使用旧版本，之后的识别使用新版本；新配置不合理时拒绝更新并保留旧版本
+// 后台推送配置时建议先写临时文件再重命名，避免读到写了一半的文件
+type Watcher struct {
+	path       string
+	recognizer *recognition.WeightRecognizer
+
+	OnReload func(*Config) // 成功切换到新配置后调用，可以为空
+	OnError  func(error)   // 读取或校验新配置失败时调用，可以为空
+
+	mu      sync.Mutex
+	current *Config
+	modTime time.Time
+	size    int64
+	sum     [sha256.Size]byte // 最近一次读取的文件内容摘要，无论是否被接受
+}
+
+// NewWatcher 创建配置文件监视器，current 为创建识别器时使用的配置
+func NewWatcher(path string, recognizer *recognition.WeightRecognizer, current *Config) (*Watcher, error) {
+	w := &Watcher{path: path, recognizer: recognizer, current: current}
+	data, info, err := w.read()
+	if err != nil {
+		return nil, err
+	}
+	w.remember(data, info)
+	return w, nil
+}
+
+// Config 返回当前生效的配置
+func (w *Watcher) Config() *Config {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.current
+}
+
+// Check 检查一次配置文件，文件内容变化且新配置被接受时返回 true
+// 新配置不合理时返回错误，识别器保持原有配置；同一份被拒绝的内容不会重复报告
+func (w *Watcher) Check() (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return false, fmt.Errorf("读取配置文件失败: %w", err)
+	}
+	if info.ModTime().Equal(w.modTime) && info.Size() == w.size {
+		return false, nil
+	}
+
+	data, info, err := w.read()
+	if err != nil {
+		return false, err
+	}
+	if sha256.Sum256(data) == w.sum {
+		w.modTime, w.size = info.ModTime(), info.Size()
+		return false, nil
+	}
+	w.remember(data, info)
+
+	cfg, err := Parse(data, formatOf(w.path))
+	if err != nil {
+		return false, fmt.Errorf("%s: 拒绝新配置: %w", w.path, err)
+	}
+	if w.current != nil && (!sameSettings(w.current.Recognizer, cfg.Recognizer) || !reflect.DeepEqual(w.current.Filters, cfg.Filters)) {
+		return false, fmt.Errorf("%s: 拒绝新配置: %w", w.path, ErrRestartRequired)
+	}
+
+	goods, stocks := cfg.Catalog()
+	positions, radius := cfg.planogram()
+	if err := w.recognizer.ReloadCatalog(goods, stocks, cfg.overrides(), positions, radius); err != nil {
+		return false, fmt.Errorf("%s: 拒绝新配置: %w", w.path, err)
+	}
+	w.current = cfg
+
+	return true, nil
+}
+
+// Run 每隔 interval 检查一次配置文件，直到 ctx 结束
+func (w *Watcher) Run(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("检查间隔必须为正数，实际为 %v", interval)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			reloaded, err := w.Check()
+			if err != nil && w.OnError != nil {
+				w.OnError(err)
+			}
+			if reloaded && w.OnReload != nil {
+				w.OnReload(w.Config())
+			}
+		}
+	}
+}
+
+// read 读取配置文件的内容和状态
+func (w *Watcher) read() ([]byte, os.FileInfo, error) {
+	info, err := os.Stat(w.path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取配置文件失败: %w", err)
+	}
+	data, err := os.ReadFile(w.path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取配置文件失败: %w", err)
+	}
+	return data, info, nil
+}
+
+// remember 记录已经处理过的文件内容和状态
+func (w *Watcher) remember(data []byte, info os.FileInfo) {
+	w.sum = sha256.Sum256(data)
+	w.modTime, w.size = info.ModTime(), info.Size()
+}
+
+// sameSettings 判断两份识别器参数中只能在创建时设置的部分是否相同，容差覆盖和货道定位半径可以热加载
+func sameSettings(a, b Recognizer) bool {
+	a.LayerSensor, a.LayerPackage, a.GoodsPackage, a.LaneRadius = nil, nil, nil, 0
+	b.LayerSensor, b.LayerPackage, b.GoodsPackage, b.LaneRadius = nil, nil, nil, 0
+	return reflect.DeepEqual(a, b)
+}
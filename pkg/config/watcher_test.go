@@ -0,0 +1,136 @@
+package config
+
+import (
+	"VendingMachineWeightRecognition/pkg/model"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeConfig 写入配置文件并设置修改时间，避免文件系统时间精度导致变化检测不到
+func writeConfig(t *testing.T, path string, content string, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestWatcher_Check 测试热加载新配置，拒绝不合理或需要重启的配置并保留旧版本
+func TestWatcher_Check(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "machine.yaml")
+	now := time.Now()
+	writeConfig(t, path, `
+layers: 1
+goods: [{id: "000001", weight: 500}]
+stocks: [{goods_id: "000001", layer: 1, num: 5}]
+recognizer: {sensor_tolerance: 5}
+`, now)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recognizer, err := cfg.NewRecognizer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	watcher, err := NewWatcher(path, recognizer, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if reloaded, err := watcher.Check(); reloaded || err != nil {
+		t.Fatalf("文件未变化时不应该重新加载: %v %v", reloaded, err)
+	}
+
+	// 新的货道规划和容差覆盖
+	writeConfig(t, path, `
+layers: 1
+goods: [{id: "000001", weight: 500}, {id: "000002", weight: 300}]
+stocks: [{goods_id: "000002", layer: 1, num: 3}]
+recognizer: {sensor_tolerance: 5, layer_sensor: {1: 8}}
+`, now.Add(time.Second))
+	if reloaded, err := watcher.Check(); !reloaded || err != nil {
+		t.Fatalf("应该加载新配置: %v %v", reloaded, err)
+	}
+	result := recognizer.Recognize([]model.Layer{{Index: 1, Weight: 900}}, []model.Layer{{Index: 1, Weight: 607}})
+	if !result.Successful || len(result.Items) != 1 || result.Items[0].GoodsID != "000002" {
+		t.Errorf("应该按新货道规划和容差覆盖识别出1个商品000002，实际结果%+v", result)
+	}
+
+	// 库存引用未知商品，拒绝并保留上一版本
+	writeConfig(t, path, `
+layers: 1
+goods: [{id: "000001", weight: 500}]
+stocks: [{goods_id: "000003", layer: 1, num: 3}]
+recognizer: {sensor_tolerance: 5}
+`, now.Add(2*time.Second))
+	if reloaded, err := watcher.Check(); reloaded || err == nil {
+		t.Fatalf("应该拒绝不合理的配置: %v %v", reloaded, err)
+	}
+	if goods := recognizer.Goods(); len(goods) != 2 || len(watcher.Config().Goods) != 2 {
+		t.Errorf("拒绝后应该保留上一版本，实际商品%v", goods)
+	}
+	if reloaded, err := watcher.Check(); reloaded || err != nil {
+		t.Errorf("同一份被拒绝的内容不应该重复报告: %v %v", reloaded, err)
+	}
+
+	// 全局传感器容差只能在创建识别器时设置
+	writeConfig(t, path, `
+layers: 1
+goods: [{id: "000001", weight: 500}]
+stocks: [{goods_id: "000001", layer: 1, num: 5}]
+recognizer: {sensor_tolerance: 10}
+`, now.Add(3*time.Second))
+	if _, err := watcher.Check(); !errors.Is(err, ErrRestartRequired) {
+		t.Errorf("应该返回需要重启的错误，实际为 %v", err)
+	}
+}
+
+// TestWatcher_Run 测试后台轮询加载新配置
+func TestWatcher_Run(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "machine.json")
+	now := time.Now()
+	writeConfig(t, path, `{"layers": 1, "goods": [{"id": "000001", "weight": 500}], "recognizer": {"sensor_tolerance": 5}}`, now)
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recognizer, err := cfg.NewRecognizer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	watcher, err := NewWatcher(path, recognizer, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := make(chan *Config, 1)
+	watcher.OnReload = func(cfg *Config) { reloaded <- cfg }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- watcher.Run(ctx, 5*time.Millisecond) }()
+
+	writeConfig(t, path, `{"layers": 2, "goods": [{"id": "000001", "weight": 500}], "recognizer": {"sensor_tolerance": 5}}`, now.Add(time.Second))
+	select {
+	case cfg := <-reloaded:
+		if cfg.Layers != 2 {
+			t.Errorf("应该加载新配置，实际层数为 %d", cfg.Layers)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("超时未加载新配置")
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("ctx 结束后应该返回 context.Canceled，实际为 %v", err)
+	}
+}
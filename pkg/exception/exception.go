@@ -7,4 +7,9 @@ const (
 	SensorError ExceptionEnum = iota
 	ForeignObjectError
 	RecognitionError
+	PartialConsumptionError // 商品被部分饮用后放回
+	SwapSuspectedError      // 疑似取走商品后放回等重物品
+	SensorInconsistentError // 层传感器读数与整机重量不一致
+	CabinetMismatchError    // 整机重量与各层重量变化之和不一致，且无法定位到单个层
+	VibrationError          // 快照处于整机受到碰撞的时间窗口内，读数不可靠
 )
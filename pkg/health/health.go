@@ -0,0 +1,341 @@
+package health
+
+import (
+	"VendingMachineWeightRecognition/pkg/exception"
+	"VendingMachineWeightRecognition/pkg/model"
+	"VendingMachineWeightRecognition/pkg/recognition"
+	"VendingMachineWeightRecognition/pkg/sensor"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// CellID 称重单元编号，单传感器层的 Cell 为 0
+type CellID struct {
+	Layer int
+	Cell  int
+}
+
+// String 返回称重单元的可读形式
+func (id CellID) String() string {
+	return fmt.Sprintf("第%d层称重单元%d", id.Layer, id.Cell)
+}
+
+// Thresholds 需要维护的阈值，指标达到阈值时产生维护告警，健康分降到 50
+type Thresholds struct {
+	NoiseFloor     float64       // 空闲读数的噪声标准差，单位 g
+	DriftRate      float64       // 空闲读数的漂移速度绝对值，单位 g/h
+	StuckDuration  time.Duration // 读数保持完全不变、期间会话中也没有重量变化的时长
+	OutOfRangeRate float64       // 超量程读数的比例
+	ErrorRate      float64       // 有购物的会话中识别异常的比例
+	MinSessions    int           // 计算识别异常比例所需的最少会话数
+}
+
+// DefaultThresholds 返回默认阈值
+func DefaultThresholds() Thresholds {
+	return Thresholds{
+		NoiseFloor:     3,
+		DriftRate:      2,
+		StuckDuration:  6 * time.Hour,
+		OutOfRangeRate: 0.01,
+		ErrorRate:      0.1,
+		MinSessions:    20,
+	}
+}
+
+// AlertKind 维护告警类型
+type AlertKind int
+
+const (
+	NoisyAlert      AlertKind = iota // 噪声过大
+	DriftAlert                       // 零点漂移过快
+	StuckAlert                       // 读数长时间不变，疑似卡死
+	OutOfRangeAlert                  // 超量程读数过多
+	ErrorRateAlert                   // 识别异常过多
+)
+
+// String 返回告警类型的可读形式
+func (k AlertKind) String() string {
+	switch k {
+	case NoisyAlert:
+		return "noisy"
+	case DriftAlert:
+		return "drift"
+	case StuckAlert:
+		return "stuck"
+	case OutOfRangeAlert:
+		return "out-of-range"
+	case ErrorRateAlert:
+		return "error-rate"
+	default:
+		return fmt.Sprintf("AlertKind(%d)", int(k))
+	}
+}
+
+// Alert 维护告警
+type Alert struct {
+	Cell    CellID
+	Kind    AlertKind
+	Value   float64 // 指标当前值
+	Limit   float64 // 对应阈值
+	Message string
+}
+
+// CellHealth 称重单元的健康状况
+type CellHealth struct {
+	Cell       CellID
+	Readings   int           // 空闲读数次数
+	NoiseFloor float64       // 噪声标准差，单位 g
+	DriftRate  float64       // 漂移速度，单位 g/h
+	StuckFor   time.Duration // 读数保持不变的时长
+	OutOfRange int           // 超量程次数，包含会话中的传感器异常
+	Sessions   int           // 该层有购物的会话数
+	Errors     int           // 该层识别异常的会话数
+	Score      float64       // 健康分，100 为完全健康，0 为需要立即维护
+	Alerts     []Alert
+}
+
+// ErrorRate 返回识别异常比例
+func (h CellHealth) ErrorRate() float64 {
+	if h.Sessions == 0 {
+		return 0
+	}
+	return float64(h.Errors) / float64(h.Sessions)
+}
+
+// OutOfRangeRate 返回超量程读数比例
+func (h CellHealth) OutOfRangeRate() float64 {
+	total := h.Readings + h.OutOfRange
+	if total == 0 {
+		return 0
+	}
+	return float64(h.OutOfRange) / float64(total)
+}
+
+// cellHistory 称重单元的累计统计
+type cellHistory struct {
+	idle       sensor.IdleStats // 按空闲窗口分段的噪声、漂移和卡死统计
+	outOfRange int
+}
+
+// layerHistory 层的识别统计
+type layerHistory struct {
+	sessions   int
+	errors     int
+	sensorErrs int
+}
+
+// Monitor 传感器健康监测，可被多个 goroutine 并发使用
+type Monitor struct {
+	thresholds Thresholds
+
+	mu     sync.Mutex
+	cells  map[CellID]*cellHistory
+	layers map[int]*layerHistory
+}
+
+// NewMonitor 创建健康监测
+func NewMonitor(thresholds Thresholds) *Monitor {
+	return &Monitor{
+		thresholds: thresholds,
+		cells:      make(map[CellID]*cellHistory),
+		layers:     make(map[int]*layerHistory),
+	}
+}
+
+// ObserveIdle 记录无人购物时的层读数，用于估计噪声、漂移和卡死
+// 两次会话之间的读数属于同一个空闲窗口，ObserveResult 和 Interrupt 结束当前窗口，窗口之间的重量变化不计入噪声和漂移
+func (m *Monitor) ObserveIdle(layer model.Layer, at time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(layer.Cells) == 0 {
+		m.observeCell(CellID{Layer: layer.Index}, layer.Reading(), at)
+		return
+	}
+	for i, cell := range layer.Cells {
+		m.observeCell(CellID{Layer: layer.Index, Cell: i}, cell.Reading(), at)
+	}
+}
+
+// observeCell 记录一个称重单元的空闲读数，调用方需持有锁
+func (m *Monitor) observeCell(id CellID, weight model.Weight, at time.Time) {
+	history := m.cell(id)
+	if weight < 0 || weight > model.Grams(sensor.MaxWeight) {
+		history.outOfRange++
+		return
+	}
+	history.idle.Observe(weight, at)
+}
+
+// Interrupt 结束某层所有称重单元的空闲窗口，用于补货、维护等不经过识别的重量变化之后
+// 该层的读数此后不再视为卡死，直到再次长时间保持不变
+func (m *Monitor) Interrupt(layer int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, history := range m.cells {
+		if id.Layer == layer {
+			history.idle.Break(true)
+		}
+	}
+}
+
+// ObserveResult 记录一次会话的识别结果，用于统计识别异常比例和传感器异常
+func (m *Monitor) ObserveResult(result recognition.RecognitionResult) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// 会话期间可能取放了商品，结束所有层的空闲窗口；重量发生变化的层不再视为卡死
+	moved := make(map[int]bool)
+	for _, layer := range result.Layers {
+		moved[layer.Layer] = layer.Diff != 0 || len(layer.Items) > 0
+	}
+	for id := range m.cells {
+		m.cells[id].idle.Break(moved[id.Layer])
+	}
+
+	active := make(map[int]bool)
+	for _, layer := range result.Layers {
+		active[layer.Layer] = true
+	}
+	failed := make(map[int]bool)
+	for _, e := range result.Exceptions {
+		switch e.Exception {
+		case exception.SensorError, exception.SensorInconsistentError:
+			m.layer(e.Layer).sensorErrs++
+		case exception.RecognitionError:
+			active[e.Layer] = true
+			failed[e.Layer] = true
+		}
+	}
+
+	for layer := range active {
+		m.layer(layer).sessions++
+		if failed[layer] {
+			m.layer(layer).errors++
+		}
+	}
+}
+
+// Reset 清除某个称重单元的统计，用于维护或更换传感器之后
+func (m *Monitor) Reset(id CellID) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.cells, id)
+}
+
+// Report 返回所有称重单元的健康状况，按层号和称重单元编号排序
+// now 用于计算读数保持不变的时长
+func (m *Monitor) Report(now time.Time) []CellHealth {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ids := make([]CellID, 0, len(m.cells))
+	for id := range m.cells {
+		ids = append(ids, id)
+	}
+	// 只有识别结果、没有空闲读数的层也需要报告
+	for layer := range m.layers {
+		if !m.hasLayer(layer) {
+			ids = append(ids, CellID{Layer: layer})
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if ids[i].Layer != ids[j].Layer {
+			return ids[i].Layer < ids[j].Layer
+		}
+		return ids[i].Cell < ids[j].Cell
+	})
+
+	reports := make([]CellHealth, 0, len(ids))
+	for _, id := range ids {
+		reports = append(reports, m.report(id, now))
+	}
+	return reports
+}
+
+// Alerts 返回所有需要维护的告警
+func (m *Monitor) Alerts(now time.Time) []Alert {
+	alerts := make([]Alert, 0)
+	for _, report := range m.Report(now) {
+		alerts = append(alerts, report.Alerts...)
+	}
+	return alerts
+}
+
+// report 计算单个称重单元的健康状况，调用方需持有锁
+func (m *Monitor) report(id CellID, now time.Time) CellHealth {
+	health := CellHealth{Cell: id}
+	if history, ok := m.cells[id]; ok {
+		health.Readings = history.idle.Readings()
+		health.OutOfRange = history.outOfRange
+		noise, _ := history.idle.Noise()
+		health.NoiseFloor = noise.Float()
+		health.DriftRate, _ = history.idle.Drift()
+		health.StuckFor = history.idle.Stuck(now)
+	}
+	if layer, ok := m.layers[id.Layer]; ok {
+		health.Sessions = layer.sessions
+		health.Errors = layer.errors
+		health.OutOfRange += layer.sensorErrs
+	}
+
+	th := m.thresholds
+	health.Score = 100
+	check := func(kind AlertKind, value, limit float64, format string) {
+		if limit <= 0 {
+			return
+		}
+		// 指标达到阈值时健康分为 50，达到两倍阈值时为 0
+		ratio := value / limit
+		health.Score = math.Min(health.Score, 100*(1-math.Min(ratio, 2)/2))
+		if ratio >= 1 {
+			health.Alerts = append(health.Alerts, Alert{
+				Cell:    id,
+				Kind:    kind,
+				Value:   value,
+				Limit:   limit,
+				Message: fmt.Sprintf("%s: "+format, id, value, limit),
+			})
+		}
+	}
+	check(NoisyAlert, health.NoiseFloor, th.NoiseFloor, "噪声 %.2fg 超过 %.2fg")
+	check(DriftAlert, math.Abs(health.DriftRate), th.DriftRate, "漂移 %.2fg/h 超过 %.2fg/h")
+	check(StuckAlert, health.StuckFor.Hours(), th.StuckDuration.Hours(), "读数 %.1fh 未变化，超过 %.1fh")
+	check(OutOfRangeAlert, health.OutOfRangeRate(), th.OutOfRangeRate, "超量程比例 %.3f 超过 %.3f")
+	if health.Sessions >= th.MinSessions {
+		check(ErrorRateAlert, health.ErrorRate(), th.ErrorRate, "识别异常比例 %.3f 超过 %.3f")
+	}
+
+	return health
+}
+
+// cell 返回称重单元的统计，不存在时创建，调用方需持有锁
+func (m *Monitor) cell(id CellID) *cellHistory {
+	if _, exists := m.cells[id]; !exists {
+		m.cells[id] = &cellHistory{}
+	}
+	return m.cells[id]
+}
+
+// layer 返回层的统计，不存在时创建，调用方需持有锁
+func (m *Monitor) layer(index int) *layerHistory {
+	if _, exists := m.layers[index]; !exists {
+		m.layers[index] = &layerHistory{}
+	}
+	return m.layers[index]
+}
+
+// hasLayer 判断是否有该层称重单元的空闲读数，调用方需持有锁
+func (m *Monitor) hasLayer(layer int) bool {
+	for id := range m.cells {
+		if id.Layer == layer {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,158 @@
+package health
+
+import (
+	"VendingMachineWeightRecognition/pkg/exception"
+	"VendingMachineWeightRecognition/pkg/model"
+	"VendingMachineWeightRecognition/pkg/recognition"
+	"math"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// hasAlert 判断告警中是否包含某种类型
+func hasAlert(alerts []Alert, kind AlertKind) bool {
+	for _, alert := range alerts {
+		if alert.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// TestMonitor_Healthy 测试正常传感器的健康分和指标
+func TestMonitor_Healthy(t *testing.T) {
+	monitor := NewMonitor(DefaultThresholds())
+	for i := 0; i < 100; i++ {
+		weight := 5000 + []int{0, 1, -1, 0}[i%4]
+		monitor.ObserveIdle(model.Layer{Index: 1, Weight: weight}, start.Add(time.Duration(i)*time.Minute))
+	}
+
+	reports := monitor.Report(start.Add(100 * time.Minute))
+	if len(reports) != 1 {
+		t.Fatalf("应该有1个称重单元，实际为%d", len(reports))
+	}
+	report := reports[0]
+	if len(report.Alerts) != 0 || report.Score < 50 {
+		t.Errorf("正常传感器不应该告警，实际%+v", report)
+	}
+	if report.NoiseFloor <= 0 || report.NoiseFloor > 1.5 {
+		t.Errorf("噪声估计错误：%v", report.NoiseFloor)
+	}
+	if math.Abs(report.DriftRate) > 0.5 {
+		t.Errorf("没有漂移时漂移速度应该接近0，实际为%v", report.DriftRate)
+	}
+}
+
+// TestMonitor_Drift 测试检测零点漂移
+func TestMonitor_Drift(t *testing.T) {
+	monitor := NewMonitor(DefaultThresholds())
+	for i := 0; i < 48; i++ {
+		weight := 5000 + i*5 + i%2 // 每小时漂移 5g
+		monitor.ObserveIdle(model.Layer{Index: 1, Weight: weight}, start.Add(time.Duration(i)*time.Hour))
+	}
+
+	report := monitor.Report(start.Add(48 * time.Hour))[0]
+	if math.Abs(report.DriftRate-5) > 0.1 {
+		t.Errorf("漂移速度应该约为5g/h，实际为%v", report.DriftRate)
+	}
+	if !hasAlert(report.Alerts, DriftAlert) || report.Score != 0 {
+		t.Errorf("应该产生漂移告警，实际%+v", report)
+	}
+}
+
+// TestMonitor_SalesBetweenIdleWindows 测试会话之间的销售不计入噪声、漂移和卡死
+func TestMonitor_SalesBetweenIdleWindows(t *testing.T) {
+	monitor := NewMonitor(DefaultThresholds())
+	weight := 5000
+	for hour := 0; hour < 12; hour++ {
+		// 安静的传感器在空闲窗口内读数完全不变
+		for i := 0; i < 12; i++ {
+			monitor.ObserveIdle(model.Layer{Index: 1, Weight: weight}, start.Add(time.Duration(hour)*time.Hour+time.Duration(i)*5*time.Minute))
+		}
+		// 每小时卖出一件 5g 的商品
+		monitor.ObserveResult(recognition.RecognitionResult{
+			Successful: true,
+			Layers:     []recognition.LayerResult{{Layer: 1, WeightDiff: 5, Diff: model.Grams(5)}},
+		})
+		weight -= 5
+	}
+
+	report := monitor.Report(start.Add(12 * time.Hour))[0]
+	if len(report.Alerts) != 0 {
+		t.Errorf("销售不应该产生告警，实际%v", report.Alerts)
+	}
+	if report.NoiseFloor != 0 || report.DriftRate != 0 || report.StuckFor > time.Hour {
+		t.Errorf("噪声、漂移和卡死时长应该只统计窗口内的读数，实际%+v", report)
+	}
+
+	// 补货后读数变化，同样不计入统计
+	monitor.Interrupt(1)
+	monitor.ObserveIdle(model.Layer{Index: 1, Weight: 6000}, start.Add(13*time.Hour))
+	monitor.ObserveIdle(model.Layer{Index: 1, Weight: 6000}, start.Add(14*time.Hour))
+	if alerts := monitor.Alerts(start.Add(14 * time.Hour)); len(alerts) != 0 {
+		t.Errorf("补货不应该产生告警，实际%v", alerts)
+	}
+}
+
+// TestMonitor_StuckAndNoisyCells 测试多传感器层中分别检测卡死和噪声过大的称重单元
+func TestMonitor_StuckAndNoisyCells(t *testing.T) {
+	monitor := NewMonitor(DefaultThresholds())
+	for i := 0; i < 100; i++ {
+		noisy := 2000 + []int{0, 20, -20, 10}[i%4]
+		precise := 1500 + []float64{0, 0.3, -0.3, 0.1}[i%4] // 只有精确读数，克以下的波动不是卡死
+		monitor.ObserveIdle(model.Layer{Index: 2, Cells: []model.Cell{
+			{Position: 0, Weight: 2000},
+			{Position: 0.5, Weight: noisy},
+			{Position: 1, Precise: model.GramsFloat(precise)},
+		}}, start.Add(time.Duration(i)*5*time.Minute))
+	}
+
+	reports := monitor.Report(start.Add(9 * time.Hour))
+	if len(reports) != 3 {
+		t.Fatalf("应该有3个称重单元，实际为%d", len(reports))
+	}
+	if len(reports[2].Alerts) != 0 {
+		t.Errorf("称重单元2应该按精确读数统计，不应该告警，实际%v", reports[2].Alerts)
+	}
+	if !hasAlert(reports[0].Alerts, StuckAlert) || hasAlert(reports[0].Alerts, NoisyAlert) {
+		t.Errorf("称重单元0应该只有卡死告警，实际%v", reports[0].Alerts)
+	}
+	if !hasAlert(reports[1].Alerts, NoisyAlert) || hasAlert(reports[1].Alerts, StuckAlert) {
+		t.Errorf("称重单元1应该只有噪声告警，实际%v", reports[1].Alerts)
+	}
+}
+
+// TestMonitor_RecognitionErrors 测试统计识别异常比例和传感器异常
+func TestMonitor_RecognitionErrors(t *testing.T) {
+	monitor := NewMonitor(DefaultThresholds())
+	for i := 0; i < 30; i++ {
+		result := recognition.RecognitionResult{
+			Layers: []recognition.LayerResult{{Layer: 1}},
+		}
+		if i%3 == 0 {
+			result.Exceptions = append(result.Exceptions, recognition.RecognitionException{Layer: 1, Exception: exception.RecognitionError})
+		}
+		if i%10 == 0 {
+			result.Exceptions = append(result.Exceptions, recognition.RecognitionException{Layer: 3, Exception: exception.SensorError})
+		}
+		monitor.ObserveResult(result)
+	}
+
+	reports := monitor.Report(start)
+	if len(reports) != 2 {
+		t.Fatalf("应该报告第1层和第3层，实际为%+v", reports)
+	}
+	if reports[0].Sessions != 30 || reports[0].Errors != 10 || !hasAlert(reports[0].Alerts, ErrorRateAlert) {
+		t.Errorf("第1层应该有识别异常告警，实际%+v", reports[0])
+	}
+	if reports[1].OutOfRange != 3 || !hasAlert(reports[1].Alerts, OutOfRangeAlert) {
+		t.Errorf("第3层应该有超量程告警，实际%+v", reports[1])
+	}
+
+	monitor.Reset(CellID{Layer: 1})
+	if alerts := monitor.Alerts(start); len(alerts) != 2 {
+		t.Errorf("重置空闲读数不影响识别统计，实际告警%v", alerts)
+	}
+}
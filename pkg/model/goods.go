@@ -1,7 +1,124 @@
 package model
 
+import "time"
+
+// GoodsKind 商品的计量方式
+type GoodsKind int
+
+const (
+	UnitGoods    GoodsKind = iota // 按件销售，单件重量固定
+	WeighedGoods                  // 按重量销售，如水果、熟食
+)
+
+// BundleComponent 组合装中包含的商品
+type BundleComponent struct {
+	GoodsID string // 所含商品的编号
+	Num     int    // 所含数量
+}
+
+// GoodsFlag 商品标记，可以按位组合
+type GoodsFlag uint
+
+const (
+	AgeRestricted GoodsFlag = 1 << iota // 烟酒等需要验证年龄的商品
+	Refrigerated                        // 需要冷藏
+	Fragile                             // 易碎商品
+)
+
+// Has 是否包含标记 flag
+func (f GoodsFlag) Has(flag GoodsFlag) bool {
+	return f&flag == flag
+}
+
+// GoodsInfo 商品的描述信息，不参与识别，随识别结果返回，供生成订单使用
+type GoodsInfo struct {
+	Names    map[string]string // 语言代码到商品名称的映射，如 zh-CN、en
+	Barcode  string            // 商品条码（GTIN）
+	Price    int               // 单价，单位分，称重商品为每千克的价格
+	Category string            // 商品分类
+	Flags    GoodsFlag         // 商品标记
+}
+
+// Name 返回第一个有名称的语言的商品名称，都没有时返回空字符串
+func (i GoodsInfo) Name(languages ...string) string {
+	for _, language := range languages {
+		if name, ok := i.Names[language]; ok {
+			return name
+		}
+	}
+	return ""
+}
+
+// Clone 返回描述信息的副本，不与原对象共享名称映射
+func (i GoodsInfo) Clone() GoodsInfo {
+	if i.Names != nil {
+		names := make(map[string]string, len(i.Names))
+		for language, name := range i.Names {
+			names[language] = name
+		}
+		i.Names = names
+	}
+	return i
+}
+
+// GoodsRevision 商品重量的一个版本，供应商更换包装后从 EffectiveFrom 起生效
+type GoodsRevision struct {
+	EffectiveFrom time.Time // 生效时间
+	Weight        int       // 单件重量，单位 g
+	Precise       Weight    // 精确的单件重量，非零时优先于 Weight
+}
+
 // Goods 表示商品信息
 type Goods struct {
-	ID     string // 6 位的商品编号，每个商品唯一
-	Weight int    // 商品单件重量，单位 g
+	ID      string    // 6 位的商品编号，每个商品唯一
+	Weight  int       // 商品单件重量，单位 g，称重商品不使用
+	Precise Weight    // 精确的单件重量，非零时优先于 Weight，用于口香糖等轻量商品
+	Kind    GoodsKind // 计量方式，默认按件销售
+
+	MinPortion int // 称重商品单次取走的最小合理重量，单位 g
+	MaxPortion int // 称重商品单次取走的最大合理重量，单位 g
+
+	EmptyWeight int // 空容器重量，单位 g，大于 0 时可以检测部分饮用后放回的商品
+
+	Components []BundleComponent // 组合装包含的商品，如6瓶装；为空表示普通商品
+
+	Revisions []GoodsRevision // 重量版本，为空表示重量不随时间变化；Weight 和 Precise 为最早版本生效前的重量
+
+	Info GoodsInfo // 名称、条码、价格等描述信息
+}
+
+// UnitWeight 返回单件重量，优先使用精确重量
+func (g Goods) UnitWeight() Weight {
+	if g.Precise != 0 {
+		return g.Precise
+	}
+	return Grams(g.Weight)
+}
+
+// IsBundle 是否为由其他商品组成的组合装
+func (g Goods) IsBundle() bool {
+	return len(g.Components) > 0
+}
+
+// RevisionAt 返回 t 时刻生效的重量版本在 Revisions 中的下标，即生效时间不晚于 t 的最新版本，没有时返回 -1
+func (g Goods) RevisionAt(t time.Time) int {
+	latest := -1
+	for i, revision := range g.Revisions {
+		if revision.EffectiveFrom.After(t) {
+			continue
+		}
+		if latest < 0 || revision.EffectiveFrom.After(g.Revisions[latest].EffectiveFrom) {
+			latest = i
+		}
+	}
+	return latest
+}
+
+// At 返回 t 时刻生效的商品信息，Weight 和 Precise 取该时刻生效的版本
+func (g Goods) At(t time.Time) Goods {
+	if r := g.RevisionAt(t); r >= 0 {
+		g.Weight = g.Revisions[r].Weight
+		g.Precise = g.Revisions[r].Precise
+	}
+	return g
 }
@@ -2,6 +2,31 @@ package model
 
 // Layer 表示售货机的一层
 type Layer struct {
-	Index  int // 编号，从 1 开始
-	Weight int // 重量传感器数值，单位 g
+	Index   int    // 编号，从 1 开始
+	Weight  int    // 重量传感器数值，单位 g，多传感器层为各称重单元之和
+	Precise Weight // 精确的传感器读数，非零时优先于 Weight
+	Cells   []Cell // 多传感器层各称重单元的读数，为空表示单传感器层
+}
+
+// Reading 返回层读数，优先使用精确读数
+func (l Layer) Reading() Weight {
+	if l.Precise != 0 {
+		return l.Precise
+	}
+	return Grams(l.Weight)
+}
+
+// Cell 表示多传感器层中的一个称重单元
+type Cell struct {
+	Position float64 // 称重单元在层上的横向位置，0 为最左端，1 为最右端
+	Weight   int     // 称重单元读数，单位 g
+	Precise  Weight  // 精确的称重单元读数，非零时优先于 Weight
+}
+
+// Reading 返回称重单元读数，优先使用精确读数
+func (c Cell) Reading() Weight {
+	if c.Precise != 0 {
+		return c.Precise
+	}
+	return Grams(c.Weight)
 }
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// Sample 表示购物过程中某层的一次中间读数
+type Sample struct {
+	Layer   int       // 层编号，从 1 开始
+	Weight  int       // 重量传感器数值，单位 g
+	Precise Weight    // 精确的传感器读数，非零时优先于 Weight
+	Time    time.Time // 读数时间
+}
+
+// Reading 返回读数，优先使用精确读数
+func (s Sample) Reading() Weight {
+	if s.Precise != 0 {
+		return s.Precise
+	}
+	return Grams(s.Weight)
+}
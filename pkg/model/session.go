@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+// CabinetWeight 表示整机重量传感器在购物前后的读数
+type CabinetWeight struct {
+	Begin        int    // 购物前的整机重量，单位 g
+	End          int    // 购物后的整机重量，单位 g
+	BeginPrecise Weight // 精确的购物前整机重量，非零时优先于 Begin
+	EndPrecise   Weight // 精确的购物后整机重量，非零时优先于 End
+}
+
+// BeginReading 返回购物前的整机读数，优先使用精确读数
+func (c CabinetWeight) BeginReading() Weight {
+	if c.BeginPrecise != 0 {
+		return c.BeginPrecise
+	}
+	return Grams(c.Begin)
+}
+
+// EndReading 返回购物后的整机读数，优先使用精确读数
+func (c CabinetWeight) EndReading() Weight {
+	if c.EndPrecise != 0 {
+		return c.EndPrecise
+	}
+	return Grams(c.End)
+}
+
+// Session 表示一次购物会话的传感器数据
+type Session struct {
+	BeginLayers []Layer        // 购物前各层读数
+	EndLayers   []Layer        // 购物后各层读数
+	Cabinet     *CabinetWeight // 整机重量读数，没有整机传感器时为空
+	Samples     []Sample       // 购物过程中的中间读数，可选
+	BeginTime   time.Time      // 购物前快照的时间，可选
+	EndTime     time.Time      // 购物后快照的时间，可选
+}
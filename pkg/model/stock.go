@@ -4,5 +4,5 @@ package model
 type Stock struct {
 	GoodsID string // 库存对应的商品
 	Layer   int    // 库存对应的层架
-	Num     int    // 库存数量
+	Num     int    // 库存数量，称重商品为库存重量，单位 g
 }
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"math"
+	"strconv"
+)
+
+// Weight 定点重量，单位 mg，用于表示克以下的精度
+type Weight int64
+
+const (
+	Milligram Weight = 1
+	Gram      Weight = 1000 * Milligram
+)
+
+// Grams 将整数克转换为 Weight
+func Grams(grams int) Weight {
+	return Weight(grams) * Gram
+}
+
+// GramsFloat 将浮点数克转换为 Weight，精确到 mg
+func GramsFloat(grams float64) Weight {
+	return Weight(math.Round(grams * float64(Gram)))
+}
+
+// Float 返回以克为单位的浮点数
+func (w Weight) Float() float64 {
+	return float64(w) / float64(Gram)
+}
+
+// RoundGrams 返回四舍五入后的整数克
+func (w Weight) RoundGrams() int {
+	return int(math.Round(w.Float()))
+}
+
+// String 返回重量的可读形式，例如 3.5g
+func (w Weight) String() string {
+	return strconv.FormatFloat(w.Float(), 'f', -1, 64) + "g"
+}
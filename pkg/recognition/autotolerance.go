@@ -0,0 +1,82 @@
+package recognition
+
+import (
+	"VendingMachineWeightRecognition/pkg/model"
+	"fmt"
+	"math"
+)
+
+// AutoSensorTolerance 根据空闲读数的噪声自动确定各层传感器容差的参数
+// 有效容差为 K 倍噪声标准差，限制在 [Min, Max] 范围内；分层传感器容差覆盖优先于自动容差
+type AutoSensorTolerance struct {
+	K          float64      // 容差为噪声标准差的倍数，例如 3
+	MinSamples int          // 估计噪声所需的最少空闲读数差个数，不足时使用全局传感器容差
+	Min        model.Weight // 容差下限
+	Max        model.Weight // 容差上限，0 表示只受量程限制
+}
+
+// Validate 检查自动容差参数是否合理
+func (a AutoSensorTolerance) Validate() error {
+	if math.IsNaN(a.K) || a.K <= 0 {
+		return fmt.Errorf("噪声倍数必须为正数，实际为 %v", a.K)
+	}
+	if a.MinSamples < 1 {
+		return fmt.Errorf("最少空闲读数差个数至少为 1，实际为 %d", a.MinSamples)
+	}
+	if err := validateSensorTolerance(a.Min); err != nil {
+		return fmt.Errorf("容差下限: %w", err)
+	}
+	if err := validateSensorTolerance(a.Max); err != nil {
+		return fmt.Errorf("容差上限: %w", err)
+	}
+	if a.Max != 0 && a.Max < a.Min {
+		return fmt.Errorf("容差上限 %v 小于下限 %v", a.Max, a.Min)
+	}
+	return nil
+}
+
+// tolerance 根据噪声标准差计算容差
+func (a AutoSensorTolerance) tolerance(sigma model.Weight) model.Weight {
+	tolerance := model.Weight(math.Ceil(a.K * float64(sigma)))
+	if tolerance < a.Min {
+		tolerance = a.Min
+	}
+	if a.Max != 0 && tolerance > a.Max {
+		tolerance = a.Max
+	}
+	if limit := model.Grams(maxSensorWeight); tolerance > limit {
+		tolerance = limit
+	}
+	return tolerance
+}
+
+// ObserveIdle 记录无人购物时的读数，用于估计各层噪声；同一层的读数需要按时间顺序输入
+// 每次调用为一个空闲窗口，不要把购物或补货前后的读数放在同一次调用中，窗口之间的重量跳变不计入噪声
+// 启用自动容差时，读数足够的层立即改用按噪声计算的传感器容差
+func (wr *WeightRecognizer) ObserveIdle(samples ...model.Sample) {
+	wr.noise.Observe(samples...)
+	if wr.autoSensor == nil {
+		return
+	}
+
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+
+	current := wr.state.Load()
+	next := *current
+	next.autoSensor = make(map[int]model.Weight, len(current.autoSensor))
+	for layer, tolerance := range current.autoSensor {
+		next.autoSensor[layer] = tolerance
+	}
+	for _, layer := range wr.noise.Layers() {
+		if sigma, n := wr.noise.Sigma(layer); n >= wr.autoSensor.MinSamples {
+			next.autoSensor[layer] = wr.autoSensor.tolerance(sigma)
+		}
+	}
+	wr.state.Store(&next)
+}
+
+// NoiseFloor 返回某层空闲读数的噪声标准差及参与估计的读数差个数
+func (wr *WeightRecognizer) NoiseFloor(layer int) (model.Weight, int) {
+	return wr.noise.Sigma(layer)
+}
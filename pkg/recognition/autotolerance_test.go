@@ -0,0 +1,107 @@
+package recognition
+
+import (
+	"VendingMachineWeightRecognition/pkg/model"
+	"testing"
+)
+
+// idleSamples 生成在 base 附近交替波动 amplitude 的空闲读数
+func idleSamples(layer int, base, amplitude float64, n int) []model.Sample {
+	samples := make([]model.Sample, n)
+	for i := range samples {
+		samples[i] = model.Sample{Layer: layer, Precise: model.GramsFloat(base + []float64{amplitude, -amplitude}[i%2])}
+	}
+	return samples
+}
+
+// TestWeightRecognizer_AutoSensorTolerance 测试按噪声自动确定各层传感器容差并在结果中报告
+func TestWeightRecognizer_AutoSensorTolerance(t *testing.T) {
+	goods := []model.Goods{{ID: "000001", Weight: 100}}
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 10},
+		{GoodsID: "000001", Layer: 2, Num: 10},
+	}
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance: 5,
+		AutoSensor:      &AutoSensorTolerance{K: 3, MinSamples: 50, Min: model.Grams(1)},
+		Goods:           goods,
+		Stocks:          stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	begin := []model.Layer{{Index: 1, Weight: 1000}, {Index: 2, Weight: 1000}}
+	end := []model.Layer{{Index: 1, Weight: 888}, {Index: 2, Weight: 888}}
+
+	// 空闲读数不足时使用全局容差，12g 超出容差
+	result := recognizer.Recognize(begin, end)
+	if result.SensorTolerances[1] != model.Grams(5) || len(result.Exceptions) != 2 {
+		t.Fatalf("应该使用全局容差5g，实际结果%v %v", result.SensorTolerances, result.Exceptions)
+	}
+
+	// 第1层噪声很小，第2层噪声较大
+	recognizer.ObserveIdle(idleSamples(1, 1000, 0.5, 100)...)
+	recognizer.ObserveIdle(idleSamples(2, 1000, 3, 100)...)
+
+	if sigma, n := recognizer.NoiseFloor(2); n != 99 || sigma != model.GramsFloat(4.243) {
+		t.Errorf("第2层噪声估计错误：%v（%d个读数差）", sigma, n)
+	}
+
+	result = recognizer.Recognize(begin, end)
+	if result.SensorTolerances[1] >= model.Grams(5) || result.SensorTolerances[2] <= model.Grams(12) {
+		t.Fatalf("第1层容差应该变小，第2层容差应该变大，实际为%v", result.SensorTolerances)
+	}
+	if len(result.Exceptions) != 1 || result.Exceptions[0].Layer != 1 {
+		t.Errorf("第1层应该无法识别，实际结果%v", result.Exceptions)
+	}
+	if len(result.Items) != 1 || result.Items[0].Num != 1 {
+		t.Errorf("第2层应该识别出1个商品，实际识别出%v", result.Items)
+	}
+
+	// 分层覆盖优先于自动容差
+	if err := recognizer.SetToleranceOverrides(ToleranceOverrides{LayerSensor: map[int]model.Weight{2: model.Grams(2)}}); err != nil {
+		t.Fatal(err)
+	}
+	if result := recognizer.Recognize(begin, end); result.SensorTolerances[2] != model.Grams(2) {
+		t.Errorf("第2层应该使用覆盖的容差2g，实际为%v", result.SensorTolerances[2])
+	}
+}
+
+// TestAutoSensorTolerance_Validate 测试拒绝不合理的自动容差参数
+func TestAutoSensorTolerance_Validate(t *testing.T) {
+	invalid := []AutoSensorTolerance{
+		{K: 0, MinSamples: 10},
+		{K: 3, MinSamples: 0},
+		{K: 3, MinSamples: 10, Min: model.Grams(5), Max: model.Grams(2)},
+		{K: 3, MinSamples: 10, Min: -model.Milligram},
+	}
+	for _, auto := range invalid {
+		if auto.Validate() == nil {
+			t.Errorf("参数%+v应该被拒绝", auto)
+		}
+	}
+}
+
+// TestWeightRecognizer_AutoSensorToleranceAcrossPurchase 测试两次空闲读数之间的购物不会抬高自动容差
+func TestWeightRecognizer_AutoSensorToleranceAcrossPurchase(t *testing.T) {
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance: 5,
+		AutoSensor:      &AutoSensorTolerance{K: 3, MinSamples: 50},
+		Goods:           []model.Goods{{ID: "000001", Weight: 100}},
+		Stocks:          []model.Stock{{GoodsID: "000001", Layer: 1, Num: 10}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 卖出一件商品前后各有一个空闲窗口
+	recognizer.ObserveIdle(idleSamples(1, 1000, 0.5, 100)...)
+	recognizer.ObserveIdle(idleSamples(1, 900, 0.5, 100)...)
+
+	result := recognizer.Recognize([]model.Layer{{Index: 1, Weight: 900}}, []model.Layer{{Index: 1, Weight: 800}})
+	if tolerance := result.SensorTolerances[1]; tolerance > model.Grams(3) {
+		t.Errorf("容差应该只反映窗口内的噪声，实际为%v", tolerance)
+	}
+}
@@ -0,0 +1,154 @@
+package recognition
+
+import (
+	"VendingMachineWeightRecognition/pkg/model"
+)
+
+// resolveBundleWeights 复制商品目录，并为没有填写重量的组合装按所含商品计算重量
+// 组合装可以包含组合装，按所含商品递归计算；循环包含的组合装无法计算，重量保持为 0
+// 收缩膜等包装材料有重量，建议直接填写称量得到的组合装重量
+func resolveBundleWeights(goods []model.Goods) []model.Goods {
+	resolved := make([]model.Goods, len(goods))
+	index := make(map[string]int, len(goods))
+	for i, good := range goods {
+		good.Components = append([]model.BundleComponent(nil), good.Components...)
+		resolved[i] = good
+		index[good.ID] = i
+	}
+
+	weights := make(map[string]model.Weight, len(goods))
+	visiting := make(map[string]bool)
+	var weightOf func(id string) model.Weight
+	weightOf = func(id string) model.Weight {
+		if weight, ok := weights[id]; ok {
+			return weight
+		}
+		i, exists := index[id]
+		if !exists || visiting[id] {
+			return 0
+		}
+		good := resolved[i]
+		if !good.IsBundle() || good.UnitWeight() > 0 {
+			weights[id] = good.UnitWeight()
+			return weights[id]
+		}
+
+		visiting[id] = true
+		var weight model.Weight
+		for _, component := range good.Components {
+			componentWeight := weightOf(component.GoodsID)
+			if componentWeight <= 0 {
+				weight = 0
+				break
+			}
+			weight += componentWeight * model.Weight(component.Num)
+		}
+		visiting[id] = false
+		weights[id] = weight
+		return weight
+	}
+
+	for i, good := range resolved {
+		if !good.IsBundle() || good.UnitWeight() > 0 {
+			continue
+		}
+		weight := weightOf(good.ID)
+		resolved[i].Weight = weight.RoundGrams()
+		resolved[i].Precise = weight
+	}
+
+	return resolved
+}
+
+// ExpandBundles 将识别结果中的组合装展开为所含单品，用于按单品统计销量
+// 组合装中的组合装继续展开；不在目录中的商品、普通商品和循环包含的组合装原样保留
+func (wr *WeightRecognizer) ExpandBundles(items []RecognitionItem) []RecognitionItem {
+	snap := wr.state.Load()
+	bundles := make(map[string][]model.BundleComponent)
+	for _, good := range snap.goods {
+		if good.IsBundle() {
+			bundles[good.ID] = good.Components
+		}
+	}
+
+	expanded := make([]RecognitionItem, 0, len(items))
+	visiting := make(map[string]bool)
+	var expand func(item RecognitionItem)
+	expand = func(item RecognitionItem) {
+		components, ok := bundles[item.GoodsID]
+		if !ok || visiting[item.GoodsID] {
+			expanded = append(expanded, item)
+			return
+		}
+		visiting[item.GoodsID] = true
+		for _, component := range components {
+			expand(RecognitionItem{
+				GoodsID: component.GoodsID,
+				Num:     component.Num * item.Num,
+			})
+		}
+		visiting[item.GoodsID] = false
+	}
+	for _, item := range items {
+		expand(item)
+	}
+
+	expanded = wr.mergeItems(expanded, nil)
+	snap.describe(expanded)
+	return expanded
+}
+
+// bundleAmbiguous 判断识别结果中的组合装换成等量的散装单品后是否仍在容差范围内
+// 组合装件数更少，但件数只是先验，不能据此区分整包和散装：只有收缩膜等包装重量使两者的重量差超出容差，
+// 或货道定位排除了散装单品时，才能确定取走的是整包；否则视为无法识别
+// goods 为参与识别的商品，与 window 中的允许偏差一一对应
+func bundleAmbiguous(snap *catalogSnapshot, goods []model.Goods, layer int, window weightWindow, items []RecognitionItem) bool {
+	index := make(map[string]int, len(goods))
+	for i, good := range goods {
+		index[good.ID] = i
+	}
+	quantities := make([]int, len(goods))
+	for _, item := range items {
+		if i, ok := index[item.GoodsID]; ok {
+			quantities[i] += item.Num
+		}
+	}
+
+	for _, item := range items {
+		b, ok := index[item.GoodsID]
+		if !ok || !goods[b].IsBundle() {
+			continue
+		}
+
+		// 将一个组合装换成所含的单品，单品需要在同一范围内且库存足够
+		loose := append([]int(nil), quantities...)
+		loose[b]--
+		possible := true
+		for _, component := range goods[b].Components {
+			i, ok := index[component.GoodsID]
+			if !ok {
+				possible = false
+				break
+			}
+			loose[i] += component.Num
+			if loose[i] > snap.layerStockMap[layer][component.GoodsID] {
+				possible = false
+				break
+			}
+		}
+		if !possible {
+			continue
+		}
+
+		weight, slack := 0, 0.0
+		for i, num := range loose {
+			weight += num * goods[i].Weight
+			slack += float64(num) * window.tol.slacks[i]
+		}
+		if window.accepts(weight, slack) {
+			return true
+		}
+	}
+
+	return false
+}
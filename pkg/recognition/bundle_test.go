@@ -0,0 +1,201 @@
+package recognition
+
+import (
+	"VendingMachineWeightRecognition/pkg/exception"
+	"VendingMachineWeightRecognition/pkg/model"
+	"testing"
+)
+
+// TestWeightRecognizer_Bundle 测试区分整包和散装单品
+func TestWeightRecognizer_Bundle(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Weight: 550},
+		{ID: "000002", Weight: 3320, Components: []model.BundleComponent{{GoodsID: "000001", Num: 6}}}, // 含20g收缩膜
+	}
+
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 12},
+		{GoodsID: "000002", Layer: 1, Num: 3},
+	}
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance: 5,
+		Goods:           goods,
+		Stocks:          stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := recognizer.Recognize([]model.Layer{{Index: 1, Weight: 20000}}, []model.Layer{{Index: 1, Weight: 16680}})
+	if len(result.Items) != 1 || result.Items[0].GoodsID != "000002" || result.Items[0].Num != 1 {
+		t.Errorf("应该识别出1个6瓶装，实际识别出%v", result.Items)
+	}
+
+	result = recognizer.Recognize([]model.Layer{{Index: 1, Weight: 20000}}, []model.Layer{{Index: 1, Weight: 18350}})
+	if len(result.Items) != 1 || result.Items[0].GoodsID != "000001" || result.Items[0].Num != 3 {
+		t.Errorf("应该识别出3个单瓶，实际识别出%v", result.Items)
+	}
+
+	result = recognizer.Recognize([]model.Layer{{Index: 1, Weight: 20000}}, []model.Layer{{Index: 1, Weight: 16130}})
+	if len(result.Items) != 2 {
+		t.Errorf("应该识别出1个6瓶装和1个单瓶，实际识别出%v", result.Items)
+	}
+}
+
+// TestWeightRecognizer_BundleWeightFromComponents 测试未填写重量的组合装按所含商品计算重量
+func TestWeightRecognizer_BundleWeightFromComponents(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Weight: 550},
+		{ID: "000002", Components: []model.BundleComponent{{GoodsID: "000001", Num: 6}}},
+	}
+	stocks := []model.Stock{{GoodsID: "000002", Layer: 1, Num: 3}}
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance: 5,
+		Goods:           goods,
+		Stocks:          stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := recognizer.Recognize([]model.Layer{{Index: 1, Weight: 10000}}, []model.Layer{{Index: 1, Weight: 6700}})
+	if len(result.Items) != 1 || result.Items[0].GoodsID != "000002" || result.Items[0].Num != 1 {
+		t.Errorf("应该识别出1个6瓶装，实际识别出%v", result.Items)
+	}
+	if goods[1].Weight != 0 {
+		t.Error("不应该修改调用方的商品目录")
+	}
+}
+
+// TestWeightRecognizer_NestedBundle 测试组合装包含组合装时递归计算重量并展开
+func TestWeightRecognizer_NestedBundle(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Weight: 550},
+		{ID: "000002", Components: []model.BundleComponent{{GoodsID: "000001", Num: 6}}},
+		{ID: "000003", Components: []model.BundleComponent{{GoodsID: "000002", Num: 4}}}, // 按所含商品计算为13200g
+	}
+	stocks := []model.Stock{
+		{GoodsID: "000003", Layer: 1, Num: 2},
+		{GoodsID: "000001", Layer: 2, Num: 10},
+	}
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance: 5,
+		Goods:           goods,
+		Stocks:          stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := recognizer.Recognize(
+		[]model.Layer{{Index: 1, Weight: 30000}, {Index: 2, Weight: 6000}},
+		[]model.Layer{{Index: 1, Weight: 16800}, {Index: 2, Weight: 5450}},
+	)
+	if len(result.Items) != 2 {
+		t.Fatalf("应该识别出1个箱装和1个单瓶，实际识别出%v", result.Items)
+	}
+
+	expanded := recognizer.ExpandBundles(result.Items)
+	if len(expanded) != 1 || expanded[0].GoodsID != "000001" || expanded[0].Num != 25 {
+		t.Errorf("展开后应该是25个单瓶，实际为%v", expanded)
+	}
+}
+
+// TestWeightRecognizer_ApplySale 测试按识别结果扣减对应商品的库存
+func TestWeightRecognizer_ApplySale(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Weight: 550},
+		{ID: "000002", Weight: 3320, Components: []model.BundleComponent{{GoodsID: "000001", Num: 6}}}, // 含20g收缩膜
+	}
+
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 12},
+		{GoodsID: "000002", Layer: 1, Num: 3},
+	}
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance: 5,
+		Goods:           goods,
+		Stocks:          stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := recognizer.Recognize([]model.Layer{{Index: 1, Weight: 20000}}, []model.Layer{{Index: 1, Weight: 16130}})
+	recognizer.ApplySale(result)
+
+	for _, stock := range recognizer.Stocks() {
+		switch stock.GoodsID {
+		case "000001":
+			if stock.Num != 11 {
+				t.Errorf("单瓶库存应该为11，实际为%d", stock.Num)
+			}
+		case "000002":
+			if stock.Num != 2 {
+				t.Errorf("6瓶装库存应该为2，实际为%d", stock.Num)
+			}
+		}
+	}
+
+	expanded := recognizer.ExpandBundles(result.Items)
+	if len(expanded) != 1 || expanded[0].GoodsID != "000001" || expanded[0].Num != 7 {
+		t.Errorf("展开后应该是7个单瓶，实际为%v", expanded)
+	}
+}
+
+// TestWeightRecognizer_BundleOrLoose 测试整包与等重的散装单品无法区分时报告识别异常
+func TestWeightRecognizer_BundleOrLoose(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Weight: 550},
+		{ID: "000002", Components: []model.BundleComponent{{GoodsID: "000001", Num: 6}}}, // 按所含商品计算为3300g
+	}
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 12},
+		{GoodsID: "000002", Layer: 1, Num: 3},
+	}
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance: 5,
+		Goods:           goods,
+		Stocks:          stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := recognizer.Recognize([]model.Layer{{Index: 1, Weight: 20000}}, []model.Layer{{Index: 1, Weight: 16700}})
+	if len(result.Exceptions) != 1 || result.Exceptions[0].Exception != exception.RecognitionError {
+		t.Errorf("1个6瓶装和6个单瓶重量相同，应该检测到识别异常，实际识别出%v", result.Items)
+	}
+
+	// 货道定位可以区分整包和散装
+	if err := recognizer.SetPlanogram(map[int]map[string]float64{
+		1: {"000001": 0.1, "000002": 0.9},
+	}, 0.2); err != nil {
+		t.Fatal(err)
+	}
+	result = recognizer.Recognize(twoCellLayer(10000, 10000), twoCellLayer(10000, 6700))
+	if len(result.Items) != 1 || result.Items[0].GoodsID != "000002" || result.Items[0].Num != 1 {
+		t.Errorf("应该识别出右侧的1个6瓶装，实际识别出%v", result.Items)
+	}
+	result = recognizer.Recognize(twoCellLayer(10000, 10000), twoCellLayer(6700, 10000))
+	if len(result.Items) != 1 || result.Items[0].GoodsID != "000001" || result.Items[0].Num != 6 {
+		t.Errorf("应该识别出左侧的6个单瓶，实际识别出%v", result.Items)
+	}
+
+	// 收缩膜的重量超出容差时可以区分，取走6个单瓶不应该识别为整包
+	goods[1].Weight = 3320
+	recognizer, err = NewWeightRecognizerWithOptions(Options{
+		SensorTolerance: 5,
+		Goods:           goods,
+		Stocks:          stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result = recognizer.Recognize([]model.Layer{{Index: 1, Weight: 20000}}, []model.Layer{{Index: 1, Weight: 16700}})
+	if len(result.Items) != 1 || result.Items[0].GoodsID != "000001" || result.Items[0].Num != 6 {
+		t.Errorf("应该识别出6个单瓶，实际识别出%v", result.Items)
+	}
+}
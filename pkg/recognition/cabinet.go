@@ -0,0 +1,96 @@
+package recognition
+
+import (
+	"VendingMachineWeightRecognition/pkg/exception"
+	"VendingMachineWeightRecognition/pkg/model"
+	"context"
+)
+
+// crossCheck 将整机重量变化与各层重量变化之和交叉校验
+// 不一致时逐层假设该层传感器故障，用整机重量减去其余各层的变化推算该层的变化
+// 只有一层的推算值能被识别时，用推算值替换该层读数并上报 SensorInconsistentError；否则上报 CabinetMismatchError
+func (wr *WeightRecognizer) crossCheck(ctx context.Context, snap *catalogSnapshot, cabinet model.CabinetWeight, readings []layerReading) []RecognitionException {
+	cabinetDiff := wr.ticks(cabinet.BeginReading() - cabinet.EndReading())
+	layerSum := 0
+	tolerance := wr.ticks(model.Grams(wr.cabinetTolerance))
+	for _, reading := range readings {
+		layerSum += reading.weightDiff
+		tolerance += wr.resolveTolerance(snap, reading.begin.Index).sensor
+	}
+	if abs(cabinetDiff-layerSum) <= tolerance {
+		return nil
+	}
+
+	// 推算值能被识别的层，优先选择自身读数无法识别的层
+	candidates := make([]int, 0)
+	unexplained := make([]int, 0)
+	for i, reading := range readings {
+		implied := cabinetDiff - (layerSum - reading.weightDiff)
+		if !wr.explains(ctx, snap, reading.begin.Index, implied) {
+			continue
+		}
+		candidates = append(candidates, i)
+		if !wr.explains(ctx, snap, reading.begin.Index, reading.weightDiff) {
+			unexplained = append(unexplained, i)
+		}
+	}
+	if len(candidates) > 1 {
+		candidates = unexplained
+	}
+	if len(candidates) != 1 {
+		return []RecognitionException{{
+			Exception:   exception.CabinetMismatchError,
+			BeginWeight: cabinet.BeginReading().RoundGrams(),
+			EndWeight:   cabinet.EndReading().RoundGrams(),
+		}}
+	}
+
+	faulty := &readings[candidates[0]]
+	faulty.weightDiff = cabinetDiff - (layerSum - faulty.weightDiff)
+	faulty.inferred = true
+
+	return []RecognitionException{{
+		Layer:       faulty.begin.Index,
+		Exception:   exception.SensorInconsistentError,
+		BeginWeight: faulty.begin.Weight,
+		EndWeight:   faulty.end.Weight,
+	}}
+}
+
+// explains 判断某层减少 weightDiff 克能否解释为无购物或某个商品组合
+func (wr *WeightRecognizer) explains(ctx context.Context, snap *catalogSnapshot, layer int, weightDiff int) bool {
+	tol := wr.resolveTolerance(snap, layer)
+	if abs(weightDiff) <= tol.sensor {
+		return true
+	}
+	if weightDiff < 0 {
+		return false
+	}
+	items, _ := wr.recognizeLayer(ctx, snap, layer, tol, weightDiff)
+	return len(items) > 0
+}
+
+// inferFaulty 用整机重量减去其余各层的变化，推算故障层的重量变化
+func (wr *WeightRecognizer) inferFaulty(cabinet model.CabinetWeight, readings []layerReading, faulty layerReading) layerReading {
+	weightDiff := wr.ticks(cabinet.BeginReading() - cabinet.EndReading())
+	for _, reading := range readings {
+		weightDiff -= reading.weightDiff
+	}
+	faulty.weightDiff = weightDiff
+	faulty.inferred = true
+	return faulty
+}
+
+// inRange 判断层读数及其各称重单元的读数是否都在量程范围内
+// 多传感器层单个称重单元超量程时，总重量可能仍在范围内但已不可信
+func inRange(layer model.Layer) bool {
+	if reading := layer.Reading(); reading < 0 || reading > model.Grams(maxSensorWeight) {
+		return false
+	}
+	for _, cell := range layer.Cells {
+		if reading := cell.Reading(); reading < 0 || reading > model.Grams(maxSensorWeight) {
+			return false
+		}
+	}
+	return true
+}
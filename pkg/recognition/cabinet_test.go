@@ -0,0 +1,274 @@
+package recognition
+
+import (
+	"VendingMachineWeightRecognition/pkg/exception"
+	"VendingMachineWeightRecognition/pkg/model"
+	"context"
+	"testing"
+)
+
+// TestWeightRecognizer_CabinetConsistent 测试整机重量与各层一致时结果不变
+func TestWeightRecognizer_CabinetConsistent(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Weight: 500},
+		{ID: "000002", Weight: 330},
+	}
+
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 10},
+		{GoodsID: "000002", Layer: 2, Num: 10},
+	}
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance:  5,
+		CabinetTolerance: 10,
+		Goods:            goods,
+		Stocks:           stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := recognizer.RecognizeSession(context.Background(), model.Session{
+		BeginLayers: []model.Layer{{Index: 1, Weight: 5000}, {Index: 2, Weight: 3300}},
+		EndLayers:   []model.Layer{{Index: 1, Weight: 4500}, {Index: 2, Weight: 2970}},
+		Cabinet:     &model.CabinetWeight{Begin: 60000, End: 59168},
+	})
+	if len(result.Exceptions) != 0 || len(result.Items) != 2 {
+		t.Errorf("应该识别出2个商品且没有异常，实际结果%v %v", result.Items, result.Exceptions)
+	}
+}
+
+// TestWeightRecognizer_CabinetLocatesFaultyLayer 测试整机重量定位读数漂移的层并继续识别
+func TestWeightRecognizer_CabinetLocatesFaultyLayer(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Weight: 500},
+		{ID: "000002", Weight: 330},
+	}
+
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 10},
+		{GoodsID: "000002", Layer: 2, Num: 10},
+	}
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance:  5,
+		CabinetTolerance: 10,
+		Goods:            goods,
+		Stocks:           stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 第2层实际取走1件，但传感器读数只减少了 120g
+	result := recognizer.RecognizeSession(context.Background(), model.Session{
+		BeginLayers: []model.Layer{{Index: 1, Weight: 5000}, {Index: 2, Weight: 3300}},
+		EndLayers:   []model.Layer{{Index: 1, Weight: 4500}, {Index: 2, Weight: 3180}},
+		Cabinet:     &model.CabinetWeight{Begin: 60000, End: 59170},
+	})
+
+	if len(result.Exceptions) != 1 || result.Exceptions[0].Exception != exception.SensorInconsistentError || result.Exceptions[0].Layer != 2 {
+		t.Fatalf("应该检测到第2层传感器不一致，实际结果%v", result.Exceptions)
+	}
+	if len(result.Items) != 2 {
+		t.Errorf("应该按推算值识别出2个商品，实际识别出%v", result.Items)
+	}
+	for _, layer := range result.Layers {
+		if layer.Inferred != (layer.Layer == 2) {
+			t.Errorf("只有第2层应该标记为推算，实际为%+v", layer)
+		}
+	}
+}
+
+// TestWeightRecognizer_CabinetMismatch 测试无法定位故障层时上报整机不一致
+func TestWeightRecognizer_CabinetMismatch(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Weight: 500},
+		{ID: "000002", Weight: 330},
+	}
+
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 10},
+		{GoodsID: "000002", Layer: 2, Num: 10},
+	}
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance:  5,
+		CabinetTolerance: 10,
+		Goods:            goods,
+		Stocks:           stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := recognizer.RecognizeSession(context.Background(), model.Session{
+		BeginLayers: []model.Layer{{Index: 1, Weight: 5000}, {Index: 2, Weight: 3300}},
+		EndLayers:   []model.Layer{{Index: 1, Weight: 4500}, {Index: 2, Weight: 2970}},
+		Cabinet:     &model.CabinetWeight{Begin: 60000, End: 58000},
+	})
+
+	if len(result.Exceptions) != 1 || result.Exceptions[0].Exception != exception.CabinetMismatchError {
+		t.Fatalf("应该检测到整机重量不一致，实际结果%v", result.Exceptions)
+	}
+	if len(result.Items) != 2 {
+		t.Errorf("无法定位时按各层读数识别，实际识别出%v", result.Items)
+	}
+}
+
+// TestWeightRecognizer_DegradedMode 测试层传感器超量程时用整机重量推算该层并识别
+func TestWeightRecognizer_DegradedMode(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Weight: 500},
+		{ID: "000002", Weight: 330},
+	}
+
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 10},
+		{GoodsID: "000002", Layer: 2, Num: 10},
+	}
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance:  5,
+		CabinetTolerance: 10,
+		Goods:            goods,
+		Stocks:           stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session := model.Session{
+		BeginLayers: []model.Layer{{Index: 1, Weight: -1}, {Index: 2, Weight: 3300}},
+		EndLayers:   []model.Layer{{Index: 1, Weight: -1}, {Index: 2, Weight: 2970}},
+	}
+
+	// 没有整机重量时跳过故障层
+	result := recognizer.RecognizeSession(context.Background(), session)
+	if len(result.Items) != 1 || result.Degraded {
+		t.Errorf("没有整机重量时应该只识别第2层，实际识别出%v", result.Items)
+	}
+
+	session.Cabinet = &model.CabinetWeight{Begin: 60000, End: 58670} // 第1层取走2件
+	result = recognizer.RecognizeSession(context.Background(), session)
+	if len(result.Exceptions) != 1 || result.Exceptions[0].Exception != exception.SensorError {
+		t.Errorf("仍然应该上报传感器异常，实际结果%v", result.Exceptions)
+	}
+	if !result.Degraded {
+		t.Error("推算识别的结果应该标记为降级")
+	}
+	if len(result.Layers) != 2 || result.Layers[0].Layer != 1 || !result.Layers[0].Inferred || result.Layers[0].WeightDiff != 1000 {
+		t.Fatalf("第1层应该按推算值识别，实际为%+v", result.Layers)
+	}
+	if items := result.Layers[0].Items; len(items) != 1 || items[0].GoodsID != "000001" || items[0].Num != 2 {
+		t.Errorf("第1层应该识别出2个商品1，实际识别出%v", items)
+	}
+}
+
+// TestWeightRecognizer_DegradedModeCell 测试单个称重单元超量程时视为层传感器故障
+func TestWeightRecognizer_DegradedModeCell(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Weight: 500},
+		{ID: "000002", Weight: 330},
+	}
+
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 10},
+		{GoodsID: "000002", Layer: 2, Num: 10},
+	}
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance:  5,
+		CabinetTolerance: 10,
+		Goods:            goods,
+		Stocks:           stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cells := func(left, right int) []model.Layer {
+		return []model.Layer{{Index: 1, Cells: []model.Cell{{Position: 0, Weight: left}, {Position: 1, Weight: right}}}}
+	}
+
+	result := recognizer.RecognizeSession(context.Background(), model.Session{
+		BeginLayers: cells(40000, -35000),
+		EndLayers:   cells(40000, -35500),
+		Cabinet:     &model.CabinetWeight{Begin: 60000, End: 59500},
+	})
+	if len(result.Exceptions) != 1 || result.Exceptions[0].Exception != exception.SensorError {
+		t.Fatalf("称重单元超量程应该上报传感器异常，实际结果%v", result.Exceptions)
+	}
+	if len(result.Items) != 1 || result.Items[0].GoodsID != "000001" || !result.Degraded {
+		t.Errorf("应该按整机重量推算识别出商品1，实际识别出%v", result.Items)
+	}
+}
+
+// TestWeightRecognizer_DegradedModeMultipleFaults 测试多层故障时无法推算
+func TestWeightRecognizer_DegradedModeMultipleFaults(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Weight: 500},
+		{ID: "000002", Weight: 330},
+	}
+
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 10},
+		{GoodsID: "000002", Layer: 2, Num: 10},
+	}
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance:  5,
+		CabinetTolerance: 10,
+		Goods:            goods,
+		Stocks:           stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := recognizer.RecognizeSession(context.Background(), model.Session{
+		BeginLayers: []model.Layer{{Index: 1, Weight: -1}, {Index: 2, Weight: 40000}},
+		EndLayers:   []model.Layer{{Index: 1, Weight: -1}, {Index: 2, Weight: 40000}},
+		Cabinet:     &model.CabinetWeight{Begin: 60000, End: 59500},
+	})
+	if len(result.Exceptions) != 2 || len(result.Items) != 0 || result.Degraded {
+		t.Errorf("多层故障时应该只上报传感器异常，实际结果%v %v", result.Items, result.Exceptions)
+	}
+}
+
+// TestWeightRecognizer_DegradedModeWithoutCabinet 测试没有整机重量时不按量程内的称重单元推算故障层
+// 商品可能横跨多个称重单元，只看正常单元会少算商品
+func TestWeightRecognizer_DegradedModeWithoutCabinet(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Weight: 500},
+		{ID: "000002", Weight: 250},
+	}
+
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 10},
+		{GoodsID: "000002", Layer: 1, Num: 10},
+	}
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance:  5,
+		CabinetTolerance: 10,
+		Goods:            goods,
+		Stocks:           stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 从两个称重单元中间取走1个商品1，右侧单元超量程
+	result := recognizer.RecognizeSession(context.Background(), model.Session{
+		BeginLayers: []model.Layer{{Index: 1, Cells: []model.Cell{{Position: 0, Weight: 3000}, {Position: 1, Weight: -35000}}}},
+		EndLayers:   []model.Layer{{Index: 1, Cells: []model.Cell{{Position: 0, Weight: 2750}, {Position: 1, Weight: -35000}}}},
+	})
+	if len(result.Exceptions) != 1 || result.Exceptions[0].Exception != exception.SensorError {
+		t.Fatalf("称重单元超量程应该上报传感器异常，实际结果%v", result.Exceptions)
+	}
+	if len(result.Layers) != 0 || len(result.Items) != 0 || result.Degraded {
+		t.Errorf("没有整机重量时应该跳过故障层，实际为%+v", result.Layers)
+	}
+}
@@ -0,0 +1,291 @@
+package recognition
+
+import (
+	"VendingMachineWeightRecognition/pkg/model"
+	"sort"
+	"time"
+)
+
+// catalogSnapshot 商品与库存的不可变快照
+// 快照创建后不再修改，更新时整体替换，识别过程始终看到一致的数据
+type catalogSnapshot struct {
+	catalog []model.Goods // 调用方提供的商品目录，包含全部重量版本
+	catalogVersion
+	stocks        []model.Stock
+	layerStockMap map[int]map[string]int // 层号到商品库存的映射
+	priors        map[int]map[string]float64
+	overrides     ToleranceOverrides
+	planogram     planogram            // 货道规划中各商品的位置
+	autoSensor    map[int]model.Weight // 层号到按噪声计算的传感器容差的映射
+
+	changes  []time.Time      // 商品重量版本的生效时间，从早到晚排序并去重
+	epoch    int              // catalogVersion 对应的版本序号，即不晚于快照创建时刻的生效时间个数
+	versions *catalogVersions // 其他版本序号的目录，按需计算，快照的浅拷贝之间共享
+}
+
+// catalogVersion 某个版本序号下与商品重量有关的数据
+type catalogVersion struct {
+	goods         []model.Goods         // 该版本生效的商品目录，组合装已计算重量
+	layerGoodsMap map[int][]model.Goods // 层号到按件商品的映射，按重量从小到大排序
+	layerWeighed  map[int][]model.Goods // 层号到称重商品的映射
+	layerIndexMap map[int]*layerIndex   // 层号到可达重量索引的映射，仅多商品层
+	layerCostMap  map[int][]float64     // 层号到单件先验代价的映射，与 layerGoodsMap 中的商品一一对应
+}
+
+// newCatalogSnapshot 根据商品、库存和购买先验创建快照，商品和库存会被复制
+// prev 不为 nil 时，沿用 prev 的容差覆盖，并复用输入未变化的层的索引
+// 按层存放的商品重量和称重商品库存换算为 resolution 的整数倍，goods 和 stocks 保持调用方的单位
+func newCatalogSnapshot(goods []model.Goods, stocks []model.Stock, priors map[int]map[string]float64, prev *catalogSnapshot, resolution model.Weight) *catalogSnapshot {
+	snap := &catalogSnapshot{
+		catalog:       copyGoods(goods),
+		stocks:        append([]model.Stock(nil), stocks...),
+		layerStockMap: make(map[int]map[string]int),
+		priors:        priors,
+		versions:      &catalogVersions{versions: make(map[int]catalogVersion)},
+	}
+	if prev != nil {
+		snap.overrides = prev.overrides
+		snap.planogram = prev.planogram
+		snap.autoSensor = prev.autoSensor
+	}
+	snap.changes = revisionTimes(snap.catalog)
+	snap.epoch = epochAt(snap.changes, time.Now())
+
+	kinds := make(map[string]model.GoodsKind, len(snap.catalog))
+	for _, good := range snap.catalog {
+		kinds[good.ID] = good.Kind
+	}
+	for _, stock := range snap.stocks {
+		if _, exists := snap.layerStockMap[stock.Layer]; !exists {
+			snap.layerStockMap[stock.Layer] = make(map[string]int)
+		}
+		snap.layerStockMap[stock.Layer][stock.GoodsID] = stock.Num
+		if kind, exists := kinds[stock.GoodsID]; exists && kind == model.WeighedGoods {
+			snap.layerStockMap[stock.Layer][stock.GoodsID] = toTicks(model.Grams(stock.Num), resolution)
+		}
+	}
+
+	var prevVersion *catalogVersion
+	if prev != nil {
+		prevVersion = &prev.catalogVersion
+	}
+	snap.catalogVersion = snap.buildVersion(snap.epoch, prevVersion, resolution)
+
+	return snap
+}
+
+// buildVersion 计算版本序号 epoch 下与商品重量有关的数据
+// prev 不为 nil 时，复用输入未变化的层的索引
+func (snap *catalogSnapshot) buildVersion(epoch int, prev *catalogVersion, resolution model.Weight) catalogVersion {
+	version := catalogVersion{
+		goods:         resolveBundleWeights(goodsAt(snap.catalog, snap.epochTime(epoch))),
+		layerGoodsMap: make(map[int][]model.Goods),
+		layerWeighed:  make(map[int][]model.Goods),
+		layerIndexMap: make(map[int]*layerIndex),
+		layerCostMap:  make(map[int][]float64),
+	}
+
+	// 初始化层商品映射
+	for _, stock := range snap.stocks {
+		// 找到对应的商品，称重商品单独存放
+		for _, good := range version.goods {
+			if good.ID != stock.GoodsID {
+				continue
+			}
+			good = scaleGoods(good, resolution)
+			if good.Kind == model.WeighedGoods {
+				version.layerWeighed[stock.Layer] = append(version.layerWeighed[stock.Layer], good)
+			} else {
+				version.layerGoodsMap[stock.Layer] = append(version.layerGoodsMap[stock.Layer], good)
+			}
+			break
+		}
+	}
+
+	// 预先排序，识别时只读不写
+	for _, layerGoods := range version.layerGoodsMap {
+		sort.SliceStable(layerGoods, func(i, j int) bool {
+			return layerGoods[i].Weight < layerGoods[j].Weight
+		})
+	}
+
+	// 为多商品层计算先验代价并预先计算可达重量索引
+	for layer, layerGoods := range version.layerGoodsMap {
+		costs := priorCosts(layerGoods, snap.priors[layer])
+		version.layerCostMap[layer] = costs
+		if len(layerGoods) < 2 {
+			continue
+		}
+		if prev != nil {
+			if idx := prev.layerIndexMap[layer]; idx != nil && idx.sameInput(layerGoods, snap.layerStockMap[layer], costs) {
+				version.layerIndexMap[layer] = idx
+				continue
+			}
+		}
+		if idx := buildLayerIndex(layerGoods, snap.layerStockMap[layer], costs); idx != nil {
+			version.layerIndexMap[layer] = idx
+		}
+	}
+
+	return version
+}
+
+// UpdateCatalog 原子地替换商品目录和库存（货道规划）
+// 正在进行的识别继续使用旧快照，之后的识别使用新快照
+func (wr *WeightRecognizer) UpdateCatalog(goods []model.Goods, stocks []model.Stock) {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+
+	current := wr.state.Load()
+	wr.state.Store(newCatalogSnapshot(goods, stocks, current.priors, current, wr.resolution))
+}
+
+// ReloadCatalog 原子地替换商品目录、库存、容差覆盖和货道规划，用于热加载配置
+// 全部在同一个快照中生效，识别不会看到新目录和旧容差覆盖或旧货道位置的组合
+// positions 和 radius 的含义与 SetPlanogram 相同；覆盖值或货道规划不合理时返回错误，识别器保持原有设置
+func (wr *WeightRecognizer) ReloadCatalog(goods []model.Goods, stocks []model.Stock, overrides ToleranceOverrides, positions map[int]map[string]float64, radius float64) error {
+	if err := overrides.Validate(); err != nil {
+		return err
+	}
+	copied := overrides.clone()
+	plan, err := newPlanogram(positions, radius)
+	if err != nil {
+		return err
+	}
+
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+
+	current := wr.state.Load()
+	next := newCatalogSnapshot(goods, stocks, current.priors, current, wr.resolution)
+	next.overrides = copied
+	next.planogram = plan
+	wr.state.Store(next)
+
+	return nil
+}
+
+// UpdateStocks 原子地替换全部库存，商品目录保持不变
+func (wr *WeightRecognizer) UpdateStocks(stocks []model.Stock) {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+
+	current := wr.state.Load()
+	wr.state.Store(newCatalogSnapshot(current.catalog, stocks, current.priors, current, wr.resolution))
+}
+
+// SetStock 更新单条库存，层上不存在该商品时新增
+func (wr *WeightRecognizer) SetStock(stock model.Stock) {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+
+	current := wr.state.Load()
+	stocks := make([]model.Stock, 0, len(current.stocks)+1)
+	found := false
+	for _, s := range current.stocks {
+		if s.Layer == stock.Layer && s.GoodsID == stock.GoodsID {
+			s.Num = stock.Num
+			found = true
+		}
+		stocks = append(stocks, s)
+	}
+	if !found {
+		stocks = append(stocks, stock)
+	}
+
+	wr.state.Store(newCatalogSnapshot(current.catalog, stocks, current.priors, current, wr.resolution))
+}
+
+// UpdateGoodsWeights 原子地更新商品单件重量，键为商品编号，值为精确重量
+// 目录中不存在的商品忽略，Weight 更新为四舍五入的整数克，Precise 保留克以下的精度
+// 商品有重量版本时更新当前生效的版本，尚未生效的版本保持不变
+func (wr *WeightRecognizer) UpdateGoodsWeights(weights map[string]model.Weight) {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+
+	current := wr.state.Load()
+	goods := copyGoods(current.catalog)
+	now := time.Now()
+	for i := range goods {
+		weight, ok := weights[goods[i].ID]
+		if !ok || weight <= 0 {
+			continue
+		}
+		if r := goods[i].RevisionAt(now); r >= 0 {
+			goods[i].Revisions[r].Weight = weight.RoundGrams()
+			goods[i].Revisions[r].Precise = weight
+		} else {
+			goods[i].Weight = weight.RoundGrams()
+			goods[i].Precise = weight
+		}
+	}
+
+	wr.state.Store(newCatalogSnapshot(goods, current.stocks, current.priors, current, wr.resolution))
+}
+
+// ApplySale 按识别结果扣减库存，按件商品扣减件数，称重商品扣减重量
+// 组合装扣减组合装自身的库存，而不是所含的单品，部分饮用后放回货架的商品不扣减，库存不会扣减为负数
+func (wr *WeightRecognizer) ApplySale(result RecognitionResult) {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+
+	sold := make(map[int]map[string]int)
+	for _, layer := range result.Layers {
+		for _, item := range layer.Items {
+			if _, exists := sold[layer.Layer]; !exists {
+				sold[layer.Layer] = make(map[string]int)
+			}
+			if item.Weight > 0 {
+				sold[layer.Layer][item.GoodsID] += item.Weight
+			} else {
+				sold[layer.Layer][item.GoodsID] += item.Num - item.Returned
+			}
+		}
+	}
+	if len(sold) == 0 {
+		return
+	}
+
+	current := wr.state.Load()
+	stocks := make([]model.Stock, 0, len(current.stocks))
+	for _, s := range current.stocks {
+		s.Num -= sold[s.Layer][s.GoodsID]
+		if s.Num < 0 {
+			s.Num = 0
+		}
+		stocks = append(stocks, s)
+	}
+
+	wr.state.Store(newCatalogSnapshot(current.catalog, stocks, current.priors, current, wr.resolution))
+}
+
+// Goods 返回当前商品目录的副本，重量为快照创建时生效的版本
+func (wr *WeightRecognizer) Goods() []model.Goods {
+	return append([]model.Goods(nil), wr.state.Load().goods...)
+}
+
+// Stocks 返回当前库存的副本
+func (wr *WeightRecognizer) Stocks() []model.Stock {
+	return append([]model.Stock(nil), wr.state.Load().stocks...)
+}
+
+// describe 为识别结果项填写商品描述信息，每项使用独立的副本
+func (snap *catalogSnapshot) describe(items []RecognitionItem) {
+	for i := range items {
+		for _, good := range snap.catalog {
+			if good.ID == items[i].GoodsID {
+				items[i].Info = good.Info.Clone()
+				break
+			}
+		}
+	}
+}
+
+// scaleGoods 将商品的各项重量换算为 resolution 的整数倍，Weight 使用精确重量
+func scaleGoods(good model.Goods, resolution model.Weight) model.Goods {
+	good.Weight = toTicks(good.UnitWeight(), resolution)
+	good.MinPortion = toTicks(model.Grams(good.MinPortion), resolution)
+	good.MaxPortion = toTicks(model.Grams(good.MaxPortion), resolution)
+	good.EmptyWeight = toTicks(model.Grams(good.EmptyWeight), resolution)
+	return good
+}
@@ -0,0 +1,224 @@
+package recognition
+
+import (
+	"VendingMachineWeightRecognition/pkg/model"
+	"sync"
+	"testing"
+)
+
+// TestWeightRecognizer_SetStock 测试单条库存更新
+func TestWeightRecognizer_SetStock(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Weight: 100},
+	}
+
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 1},
+	}
+
+	beginLayers := []model.Layer{
+		{Index: 1, Weight: 1000},
+	}
+
+	endLayers := []model.Layer{
+		{Index: 1, Weight: 800}, // 拿走2个商品，但库存只有1个
+	}
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance:  10,
+		PackageTolerance: PercentTolerance(5),
+		Goods:            goods,
+		Stocks:           stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := recognizer.Recognize(beginLayers, endLayers)
+	if len(result.Items) != 0 {
+		t.Errorf("库存不足时不应该识别出商品，实际识别出%d个", len(result.Items))
+	}
+
+	recognizer.SetStock(model.Stock{GoodsID: "000001", Layer: 1, Num: 5})
+	result = recognizer.Recognize(beginLayers, endLayers)
+	if len(result.Items) != 1 || result.Items[0].Num != 2 {
+		t.Errorf("更新库存后应该识别出2个商品，实际结果%v", result.Items)
+	}
+	if len(recognizer.Stocks()) != 1 {
+		t.Errorf("应该只有1条库存，实际有%d条", len(recognizer.Stocks()))
+	}
+}
+
+// TestWeightRecognizer_UpdateCatalog 测试整体替换商品目录
+func TestWeightRecognizer_UpdateCatalog(t *testing.T) {
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance:  10,
+		PackageTolerance: PercentTolerance(5),
+		Goods:            []model.Goods{{ID: "000001", Weight: 100}},
+		Stocks:           []model.Stock{{GoodsID: "000001", Layer: 1, Num: 10}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recognizer.UpdateCatalog(
+		[]model.Goods{{ID: "000002", Weight: 250}},
+		[]model.Stock{{GoodsID: "000002", Layer: 1, Num: 10}},
+	)
+
+	result := recognizer.Recognize(
+		[]model.Layer{{Index: 1, Weight: 1000}},
+		[]model.Layer{{Index: 1, Weight: 750}},
+	)
+	if len(result.Items) != 1 || result.Items[0].GoodsID != "000002" {
+		t.Errorf("应该按新目录识别出商品2，实际结果%v", result.Items)
+	}
+}
+
+// TestWeightRecognizer_ReloadCatalog 测试商品目录、容差覆盖和货道规划在同一个快照中替换
+func TestWeightRecognizer_ReloadCatalog(t *testing.T) {
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance: 10,
+		Goods:           []model.Goods{{ID: "000001", Weight: 100}},
+		Stocks:          []model.Stock{{GoodsID: "000001", Layer: 1, Num: 10}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	goods := []model.Goods{{ID: "000002", Weight: 250}}
+	stocks := []model.Stock{{GoodsID: "000002", Layer: 1, Num: 10}}
+	positions := map[int]map[string]float64{1: {"000002": 0.5}}
+	before := recognizer.state.Load()
+	if err := recognizer.ReloadCatalog(goods, stocks, ToleranceOverrides{}, positions, 0.2); err != nil {
+		t.Fatal(err)
+	}
+	snap := recognizer.state.Load()
+	if snap == before || snap.planogram.radius != 0.2 || snap.planogram.positions[1]["000002"] != 0.5 || len(snap.layerGoodsMap[1]) != 1 {
+		t.Errorf("新目录和货道规划应该在同一个快照中生效，实际为%+v", snap.planogram)
+	}
+
+	// 货道规划不合理时保留原有设置
+	if err := recognizer.ReloadCatalog([]model.Goods{{ID: "000003", Weight: 300}}, nil, ToleranceOverrides{}, positions, -1); err == nil {
+		t.Error("不合理的货道规划应该被拒绝")
+	}
+	if recognizer.state.Load() != snap {
+		t.Error("拒绝新配置时不应该替换快照")
+	}
+}
+
+// TestWeightRecognizer_ConcurrentUpdate 测试识别与更新并发进行
+func TestWeightRecognizer_ConcurrentUpdate(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Weight: 100},
+		{ID: "000002", Weight: 500},
+	}
+
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 10},
+		{GoodsID: "000002", Layer: 1, Num: 5},
+	}
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance:  10,
+		PackageTolerance: PercentTolerance(5),
+		Goods:            goods,
+		Stocks:           stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				result := recognizer.Recognize(
+					[]model.Layer{{Index: 1, Weight: 2000}},
+					[]model.Layer{{Index: 1, Weight: 1400}},
+				)
+				if len(result.Items) != 2 {
+					t.Errorf("应该识别出2个商品，实际识别出%d个", len(result.Items))
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 200; j++ {
+			recognizer.SetStock(model.Stock{GoodsID: "000001", Layer: 1, Num: 10 + j%3})
+			recognizer.UpdateStocks(stocks)
+		}
+	}()
+
+	wg.Wait()
+}
+
+// TestWeightRecognizer_GoodsInfo 测试识别结果附带商品描述信息
+func TestWeightRecognizer_GoodsInfo(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Weight: 500, Info: model.GoodsInfo{
+			Names:    map[string]string{"zh-CN": "啤酒", "en": "Beer"},
+			Barcode:  "4006381333931",
+			Price:    800,
+			Category: "酒类",
+			Flags:    model.AgeRestricted | model.Refrigerated,
+		}},
+		{ID: "000002", Components: []model.BundleComponent{{GoodsID: "000001", Num: 6}}, Info: model.GoodsInfo{Price: 4500}},
+	}
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 10},
+		{GoodsID: "000002", Layer: 2, Num: 2},
+	}
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance: 5,
+		Goods:           goods,
+		Stocks:          stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := recognizer.Recognize(
+		[]model.Layer{{Index: 1, Weight: 5000}, {Index: 2, Weight: 6000}},
+		[]model.Layer{{Index: 1, Weight: 4500}, {Index: 2, Weight: 3000}},
+	)
+	if len(result.Items) != 2 || len(result.Layers) != 2 {
+		t.Fatalf("应该识别出2个商品，实际结果%+v", result)
+	}
+	for _, item := range result.Items {
+		switch item.GoodsID {
+		case "000001":
+			info := item.Info
+			if info.Name("ja", "en") != "Beer" || info.Barcode != "4006381333931" || info.Price != 800 || info.Category != "酒类" {
+				t.Errorf("商品描述信息错误: %+v", info)
+			}
+			if !info.Flags.Has(model.AgeRestricted) || info.Flags.Has(model.Fragile) {
+				t.Errorf("商品标记错误: %v", info.Flags)
+			}
+		case "000002":
+			if item.Info.Price != 4500 {
+				t.Errorf("组合装价格错误: %+v", item.Info)
+			}
+		}
+	}
+	if result.Layers[0].Items[0].Info.Price != 800 {
+		t.Errorf("分层结果也应该附带描述信息: %+v", result.Layers[0].Items)
+	}
+
+	// 展开组合装后使用单品的描述信息
+	expanded := recognizer.ExpandBundles(result.Items)
+	if len(expanded) != 1 || expanded[0].Num != 7 || expanded[0].Info.Price != 800 {
+		t.Errorf("展开后应该为7件单品，实际为%+v", expanded)
+	}
+
+	// 修改结果中的名称不影响目录
+	result.Layers[0].Items[0].Info.Names["zh-CN"] = "已修改"
+	if name := recognizer.Goods()[0].Info.Name("zh-CN"); name != "啤酒" {
+		t.Errorf("目录中的名称被修改为 %q", name)
+	}
+}
@@ -0,0 +1,81 @@
+package recognition
+
+import (
+	"VendingMachineWeightRecognition/pkg/model"
+	"fmt"
+)
+
+// ConsumptionPolicy 商品被部分饮用后放回时的计费策略
+type ConsumptionPolicy int
+
+const (
+	ChargeNone         ConsumptionPolicy = iota // 不计费，只上报异常，由人工确认后处理
+	ChargeFull                                  // 按整件计费，已开封商品无法再售
+	ChargeProportional                          // 按件计费并给出饮用量，由调用方按比例折算价格
+)
+
+// minConsumedRatio 判定为部分饮用的最少饮用量占内容物重量的比例
+// 更小的重量减少可能是包装误差或传感器漂移，仍按无法识别处理
+const minConsumedRatio = 0.1
+
+// String 返回计费策略的可读形式
+func (p ConsumptionPolicy) String() string {
+	switch p {
+	case ChargeFull:
+		return "full"
+	case ChargeProportional:
+		return "proportional"
+	case ChargeNone:
+		return "none"
+	default:
+		return fmt.Sprintf("ConsumptionPolicy(%d)", int(p))
+	}
+}
+
+// detectPartial 检查重量减少是否可以解释为某件商品被部分饮用后放回
+// 候选商品需要填写空容器重量且该层有库存，减少的重量不超过内容物重量加容差
+// 且超过传感器容差加该商品的包装容差，并至少达到内容物重量的 minConsumedRatio
+// 候选商品不唯一时无法判断是哪件商品，返回 false；饮用量以识别精度为单位
+func (wr *WeightRecognizer) detectPartial(snap *catalogSnapshot, layer int, tol layerTolerance, weightDiff int) (model.Goods, int, bool) {
+	var found model.Goods
+	candidates := 0
+	for i, good := range snap.layerGoodsMap[layer] {
+		if good.EmptyWeight <= 0 || good.EmptyWeight >= good.Weight {
+			continue
+		}
+		if snap.layerStockMap[layer][good.ID] <= 0 {
+			continue
+		}
+		content := good.Weight - good.EmptyWeight
+		if float64(weightDiff) > float64(content+tol.sensor)+tol.slacks[i]+weightEpsilon {
+			continue
+		}
+		if float64(weightDiff) <= float64(tol.sensor)+tol.slacks[i]+weightEpsilon || float64(weightDiff) < float64(content)*minConsumedRatio {
+			continue
+		}
+		found = good
+		candidates++
+	}
+	if candidates != 1 {
+		return model.Goods{}, 0, false
+	}
+
+	consumed := weightDiff
+	if content := found.Weight - found.EmptyWeight; consumed > content {
+		consumed = content
+	}
+	return found, consumed, true
+}
+
+// consumptionItems 按计费策略生成部分饮用商品的识别结果项，consumed 单位为 g
+// 开封的商品仍在货架上，结果项标记为放回，ApplySale 不扣减其库存
+func (wr *WeightRecognizer) consumptionItems(good model.Goods, consumed int) []RecognitionItem {
+	switch wr.consumption {
+	case ChargeFull:
+		return []RecognitionItem{{GoodsID: good.ID, Num: 1, Returned: 1}}
+	case ChargeProportional:
+		return []RecognitionItem{{GoodsID: good.ID, Num: 1, Consumed: consumed, Returned: 1}}
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,115 @@
+package recognition
+
+import (
+	"VendingMachineWeightRecognition/pkg/exception"
+	"VendingMachineWeightRecognition/pkg/model"
+	"testing"
+)
+
+// TestWeightRecognizer_PartialConsumption 测试检测部分饮用后放回的商品
+func TestWeightRecognizer_PartialConsumption(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Weight: 550, EmptyWeight: 30},
+	}
+
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 10},
+	}
+
+	for _, tc := range []struct {
+		policy ConsumptionPolicy
+		items  int
+	}{
+		{ChargeFull, 1},
+		{ChargeProportional, 1},
+		{ChargeNone, 0},
+	} {
+		recognizer, err := NewWeightRecognizerWithOptions(Options{
+			SensorTolerance: 5,
+			Consumption:     tc.policy,
+			Goods:           goods,
+			Stocks:          stocks,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result := recognizer.Recognize([]model.Layer{{Index: 1, Weight: 6000}}, []model.Layer{{Index: 1, Weight: 5750}})
+		if len(result.Exceptions) != 1 || result.Exceptions[0].Exception != exception.PartialConsumptionError {
+			t.Fatalf("%v: 应该检测到部分饮用异常，实际结果%v", tc.policy, result.Exceptions)
+		}
+		if result.Exceptions[0].GoodsID != "000001" || result.Exceptions[0].Consumed != 250 {
+			t.Errorf("%v: 应该估计商品1被饮用250g，实际结果%+v", tc.policy, result.Exceptions[0])
+		}
+		if len(result.Items) != tc.items {
+			t.Errorf("%v: 应该计费%d项，实际识别出%v", tc.policy, tc.items, result.Items)
+		}
+		if tc.policy == ChargeProportional && result.Items[0].Consumed != 250 {
+			t.Errorf("按比例计费应该给出饮用量，实际识别出%v", result.Items)
+		}
+
+		// 开封的商品仍在货架上，不扣减库存
+		recognizer.ApplySale(result)
+		if stock := recognizer.Stocks()[0]; stock.Num != 10 {
+			t.Errorf("%v: 部分饮用后放回不应该扣减库存，实际库存为%d", tc.policy, stock.Num)
+		}
+	}
+}
+
+// TestWeightRecognizer_PartialConsumptionNotTriggered 测试整件购买、过大或过小的重量变化不视为部分饮用
+func TestWeightRecognizer_PartialConsumptionNotTriggered(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Weight: 550, EmptyWeight: 30},
+	}
+
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 10},
+	}
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance: 5,
+		Goods:           goods,
+		Stocks:          stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := recognizer.Recognize([]model.Layer{{Index: 1, Weight: 6000}}, []model.Layer{{Index: 1, Weight: 5450}})
+	if len(result.Exceptions) != 0 || len(result.Items) != 1 || result.Items[0].Num != 1 {
+		t.Errorf("应该识别出1个商品，实际结果%v %v", result.Items, result.Exceptions)
+	}
+
+	// 多于1件内容物但不足2件，不是部分饮用
+	result = recognizer.Recognize([]model.Layer{{Index: 1, Weight: 6000}}, []model.Layer{{Index: 1, Weight: 5300}})
+	if len(result.Exceptions) != 1 || result.Exceptions[0].Exception != exception.RecognitionError {
+		t.Errorf("应该检测到识别异常，实际结果%v", result.Exceptions)
+	}
+
+	// 减少的重量过小，可能是包装误差，不是部分饮用
+	result = recognizer.Recognize([]model.Layer{{Index: 1, Weight: 6000}}, []model.Layer{{Index: 1, Weight: 5988}})
+	if len(result.Exceptions) != 1 || result.Exceptions[0].Exception != exception.RecognitionError || len(result.Items) != 0 {
+		t.Errorf("过小的重量减少应该视为无法识别，实际结果%v %v", result.Items, result.Exceptions)
+	}
+
+	// 默认不计费
+	result = recognizer.Recognize([]model.Layer{{Index: 1, Weight: 6000}}, []model.Layer{{Index: 1, Weight: 5750}})
+	if len(result.Exceptions) != 1 || result.Exceptions[0].Exception != exception.PartialConsumptionError || len(result.Items) != 0 {
+		t.Errorf("默认策略应该只上报部分饮用异常，实际结果%v %v", result.Items, result.Exceptions)
+	}
+}
+
+// TestNewWeightRecognizerWithOptions_ConsumptionPolicy 测试拒绝未知的计费策略
+func TestNewWeightRecognizerWithOptions_ConsumptionPolicy(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Weight: 550, EmptyWeight: 30},
+	}
+
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 10},
+	}
+
+	if _, err := NewWeightRecognizerWithOptions(Options{Consumption: ConsumptionPolicy(7), Goods: goods, Stocks: stocks}); err == nil {
+		t.Error("未知的计费策略应该被拒绝")
+	}
+}
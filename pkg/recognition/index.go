@@ -0,0 +1,216 @@
+package recognition
+
+import (
+	"VendingMachineWeightRecognition/pkg/model"
+	"VendingMachineWeightRecognition/pkg/sensor"
+	"math"
+)
+
+const (
+	maxSensorWeight = sensor.MaxWeight    // 传感器量程上限，单位 g
+	maxIndexWeight  = 2 * maxSensorWeight // 索引覆盖的名义重量上限，以识别精度为单位
+	maxIndexWork    = 64 << 20            // 构建单层索引的计算量上限，超过时回退到组合搜索
+)
+
+// layerIndex 单层可达重量索引
+// 对每个名义重量记录先验代价最小的组合，并记录该组合是否唯一
+// 商品数量受库存限制，包装容差和传感器容差在查找时计算
+type layerIndex struct {
+	goods     []model.Goods // 层上的商品，按重量从小到大排序
+	stocks    []int         // 与 goods 对应的库存
+	costs     []float64     // 与 goods 对应的单件先验代价
+	maxWeight int           // 索引覆盖的最大名义重量
+	complete  bool          // 是否覆盖了全部库存的总重量
+	cost      []float64     // cost[w] 为名义重量 w 的最小先验代价，+Inf 表示不可达
+	ways      []uint8       // ways[w] 为先验代价最小的不同组合数，最多记到 2
+	choice    [][]uint16    // choice[g][w] 为处理到第 g 个商品时该商品所取的数量
+}
+
+// buildLayerIndex 构建单层索引，costs 为各商品的单件先验代价，规模超过上限时返回 nil
+func buildLayerIndex(goods []model.Goods, layerStock map[string]int, costs []float64) *layerIndex {
+	stocks := make([]int, len(goods))
+	totalWeight := 0
+	for i, good := range goods {
+		if good.Weight <= 0 {
+			return nil
+		}
+		stocks[i] = layerStock[good.ID]
+		if stocks[i] < 0 {
+			stocks[i] = 0
+		}
+		totalWeight += stocks[i] * good.Weight
+	}
+
+	maxWeight := min(totalWeight, maxIndexWeight)
+	size := maxWeight + 1
+
+	// 估算计算量，避免在库存很深的宽货架上构建过久
+	work := 0
+	for i, good := range goods {
+		work += min(stocks[i], maxWeight/good.Weight) + 1
+	}
+	if work*size > maxIndexWork {
+		return nil
+	}
+
+	idx := &layerIndex{
+		goods:     goods,
+		stocks:    stocks,
+		costs:     costs,
+		maxWeight: maxWeight,
+		complete:  maxWeight == totalWeight,
+		choice:    make([][]uint16, len(goods)),
+	}
+
+	cost := make([]float64, size)
+	ways := make([]uint8, size)
+	for w := 1; w < size; w++ {
+		cost[w] = math.Inf(1)
+	}
+	ways[0] = 1
+
+	nextCost := make([]float64, size)
+	nextWays := make([]uint8, size)
+
+	// 有界背包：依次加入每个商品，记录每个重量的最小先验代价和组合数
+	for g, good := range goods {
+		limit := min(stocks[g], maxWeight/good.Weight)
+		choice := make([]uint16, size)
+
+		for w := 0; w < size; w++ {
+			bestCost := math.Inf(1)
+			bestWays := 0
+			bestNum := 0
+
+			for q := 0; q <= limit && q*good.Weight <= w; q++ {
+				prev := cost[w-q*good.Weight]
+				if math.IsInf(prev, 1) {
+					continue
+				}
+				pc := prev + float64(q)*costs[g]
+				if pc < bestCost-scoreEpsilon {
+					bestCost = pc
+					bestWays = int(ways[w-q*good.Weight])
+					bestNum = q
+				} else if pc <= bestCost+scoreEpsilon {
+					bestWays += int(ways[w-q*good.Weight])
+				}
+			}
+
+			nextCost[w] = bestCost
+			nextWays[w] = uint8(min(bestWays, 2))
+			choice[w] = uint16(bestNum)
+		}
+
+		idx.choice[g] = choice
+		cost, nextCost = nextCost, cost
+		ways, nextWays = nextWays, ways
+	}
+
+	idx.cost = cost
+	idx.ways = ways
+
+	return idx
+}
+
+// lookup 查找与 weightDiff 匹配的最佳组合
+// 选择后验概率最大的组合，结果不唯一时返回 nil
+// ok 为 false 表示索引无法给出确定的结论，调用方需要回退到分支定界搜索：
+// 容差窗口超出了索引范围，或各商品容差不同导致某个名义重量下的最优组合不在容差范围内
+func (idx *layerIndex) lookup(weightDiff int, tol layerTolerance) (items []RecognitionItem, scanned int64, ok bool) {
+	window := newWeightWindow(weightDiff, tol)
+	lo, hi := window.lo, window.hi
+	if hi > idx.maxWeight {
+		if !idx.complete {
+			return nil, 0, false
+		}
+		hi = idx.maxWeight
+	}
+
+	bestWeight := -1
+	bestScore := 0.0
+	ambiguous := false
+
+	for w := lo; w <= hi; w++ {
+		if math.IsInf(idx.cost[w], 1) {
+			continue
+		}
+		scanned++
+
+		// 各商品容差比例相同时，同一名义重量下的组合允许偏差相同，只需检查最优组合
+		// 否则最优组合超出容差时，其他组合仍可能可行，交给分支定界搜索
+		slack := idx.slack(w, tol.slacks)
+		if !window.accepts(w, slack) {
+			if !window.possible(w) {
+				continue
+			}
+			return nil, scanned, false
+		}
+		score := window.logPosterior(w, slack, idx.cost[w])
+
+		if bestWeight < 0 || score > bestScore+scoreEpsilon {
+			bestWeight = w
+			bestScore = score
+			ambiguous = idx.ways[w] > 1
+		} else if score >= bestScore-scoreEpsilon {
+			ambiguous = true
+		}
+	}
+
+	if bestWeight < 0 || ambiguous {
+		return nil, scanned, true
+	}
+
+	return idx.items(bestWeight), scanned, true
+}
+
+// quantities 还原达到名义重量 w 的组合中各商品的数量
+func (idx *layerIndex) quantities(w int) []int {
+	quantities := make([]int, len(idx.goods))
+	for g := len(idx.goods) - 1; g >= 0; g-- {
+		quantities[g] = int(idx.choice[g][w])
+		w -= quantities[g] * idx.goods[g].Weight
+	}
+	return quantities
+}
+
+// slack 返回达到名义重量 w 的组合的允许偏差之和
+func (idx *layerIndex) slack(w int, slacks []float64) float64 {
+	total := 0.0
+	for g, num := range idx.quantities(w) {
+		total += float64(num) * slacks[g]
+	}
+	return total
+}
+
+// items 还原达到名义重量 w 的组合
+func (idx *layerIndex) items(w int) []RecognitionItem {
+	items := make([]RecognitionItem, 0)
+	quantities := idx.quantities(w)
+	for g := len(idx.goods) - 1; g >= 0; g-- {
+		if quantities[g] > 0 {
+			items = append(items, RecognitionItem{
+				GoodsID: idx.goods[g].ID,
+				Num:     quantities[g],
+			})
+		}
+	}
+	return items
+}
+
+// sameInput 判断索引是否由相同的商品、库存和先验构建，相同时可以复用
+func (idx *layerIndex) sameInput(goods []model.Goods, layerStock map[string]int, costs []float64) bool {
+	if len(idx.goods) != len(goods) {
+		return false
+	}
+	for i, good := range goods {
+		stock := max(layerStock[good.ID], 0)
+		if idx.goods[i].ID != good.ID || idx.goods[i].Weight != good.Weight || idx.stocks[i] != stock {
+			return false
+		}
+		if idx.costs[i] != costs[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,150 @@
+package recognition
+
+import (
+	"VendingMachineWeightRecognition/pkg/exception"
+	"VendingMachineWeightRecognition/pkg/model"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestWeightRecognizer_IndexMultipleQuantities 测试同层多商品且每种多件
+func TestWeightRecognizer_IndexMultipleQuantities(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Weight: 100},
+		{ID: "000002", Weight: 250},
+	}
+
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 10},
+		{GoodsID: "000002", Layer: 1, Num: 5},
+	}
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance:  10,
+		PackageTolerance: PercentTolerance(5),
+		Goods:            goods,
+		Stocks:           stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := recognizer.Recognize(
+		[]model.Layer{{Index: 1, Weight: 3000}},
+		[]model.Layer{{Index: 1, Weight: 2300}}, // 拿走2个商品1和2个商品2
+	)
+
+	if len(result.Items) != 2 {
+		t.Fatalf("应该识别出2个商品，实际识别出%v", result.Items)
+	}
+	for _, item := range result.Items {
+		if item.Num != 2 {
+			t.Errorf("商品%s应该识别出2件，实际识别出%d件", item.GoodsID, item.Num)
+		}
+	}
+}
+
+// TestWeightRecognizer_IndexStockLimit 测试索引遵守库存限制
+func TestWeightRecognizer_IndexStockLimit(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Weight: 100},
+		{ID: "000002", Weight: 500},
+	}
+
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 2},
+		{GoodsID: "000002", Layer: 1, Num: 5},
+	}
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance:  10,
+		PackageTolerance: PercentTolerance(5),
+		Goods:            goods,
+		Stocks:           stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	beginLayers := []model.Layer{{Index: 1, Weight: 3000}}
+	endLayers := []model.Layer{{Index: 1, Weight: 2700}} // 需要3个商品1，但库存只有2个
+
+	result := recognizer.Recognize(beginLayers, endLayers)
+	if len(result.Exceptions) != 1 || result.Exceptions[0].Exception != exception.RecognitionError {
+		t.Errorf("库存不足时应该检测到识别异常，实际结果%v", result.Exceptions)
+	}
+
+	// 补货后重新识别
+	recognizer.SetStock(model.Stock{GoodsID: "000001", Layer: 1, Num: 10})
+	result = recognizer.Recognize(beginLayers, endLayers)
+	if len(result.Items) != 1 || result.Items[0].Num != 3 {
+		t.Errorf("补货后应该识别出3个商品1，实际结果%v", result.Items)
+	}
+}
+
+// TestWeightRecognizer_IndexAmbiguous 测试不同组合件数和重量都相同时报告识别异常
+func TestWeightRecognizer_IndexAmbiguous(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Weight: 100},
+		{ID: "000002", Weight: 300},
+		{ID: "000003", Weight: 200},
+	}
+
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 10},
+		{GoodsID: "000002", Layer: 1, Num: 10},
+		{GoodsID: "000003", Layer: 1, Num: 10},
+	}
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance: 10,
+		Goods:           goods,
+		Stocks:          stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := recognizer.Recognize(
+		[]model.Layer{{Index: 1, Weight: 3000}},
+		[]model.Layer{{Index: 1, Weight: 2600}}, // 100+300 与 200+200 无法区分
+	)
+
+	if len(result.Exceptions) != 1 || result.Exceptions[0].Exception != exception.RecognitionError {
+		t.Errorf("组合不唯一时应该检测到识别异常，实际结果%v", result.Exceptions)
+	}
+}
+
+// TestWeightRecognizer_IndexWideLayer 测试宽货架上的识别速度
+func TestWeightRecognizer_IndexWideLayer(t *testing.T) {
+	goods := make([]model.Goods, 0)
+	stocks := make([]model.Stock, 0)
+	for i := 0; i < 30; i++ {
+		id := fmt.Sprintf("%06d", i+1)
+		goods = append(goods, model.Goods{ID: id, Weight: 300 + i*41 + i*i*3})
+		stocks = append(stocks, model.Stock{GoodsID: id, Layer: 1, Num: 8})
+	}
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance: 2,
+		Goods:           goods,
+		Stocks:          stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	taken := goods[29].Weight * 2 // 拿走2个最重的商品
+
+	start := time.Now()
+	for i := 0; i < 1000; i++ {
+		result := recognizer.Recognize(
+			[]model.Layer{{Index: 1, Weight: 20000}},
+			[]model.Layer{{Index: 1, Weight: 20000 - taken}},
+		)
+		if len(result.Items) != 1 || result.Items[0].Num != 2 {
+			t.Fatalf("应该识别出2个商品30，实际识别出%v", result.Items)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("1000次识别耗时过长：%v", elapsed)
+	}
+}
@@ -0,0 +1,128 @@
+package recognition
+
+import (
+	"VendingMachineWeightRecognition/pkg/model"
+	"context"
+	"fmt"
+	"math"
+)
+
+// planogram 货道规划中各商品在层上的位置
+type planogram struct {
+	positions map[int]map[string]float64 // 层号到商品位置的映射，0 为最左端，1 为最右端
+	radius    float64                    // 定位半径，距变化位置超过最近货道该距离的商品不参与优先识别
+}
+
+// SetPlanogram 设置货道规划，positions 的键为层号和商品编号，值为商品在层上的横向位置
+// 多传感器层根据各称重单元的变化估计取货位置，优先在距该位置最近的货道中识别，以区分重量相近的商品
+// radius 为定位误差，位置不在 [0, 1] 范围内或 radius 为负数时返回错误
+func (wr *WeightRecognizer) SetPlanogram(positions map[int]map[string]float64, radius float64) error {
+	plan, err := newPlanogram(positions, radius)
+	if err != nil {
+		return err
+	}
+
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+
+	current := wr.state.Load()
+	next := *current
+	next.planogram = plan
+	wr.state.Store(&next)
+
+	return nil
+}
+
+// newPlanogram 校验并复制货道规划
+func newPlanogram(positions map[int]map[string]float64, radius float64) (planogram, error) {
+	if math.IsNaN(radius) || radius < 0 {
+		return planogram{}, fmt.Errorf("定位半径不能为负数，实际为 %v", radius)
+	}
+	copied := make(map[int]map[string]float64, len(positions))
+	for layer, layerPositions := range positions {
+		copied[layer] = make(map[string]float64, len(layerPositions))
+		for goodsID, position := range layerPositions {
+			if math.IsNaN(position) || position < 0 || position > 1 {
+				return planogram{}, fmt.Errorf("第%d层商品%s: 位置必须在 [0, 1] 范围内，实际为 %v", layer, goodsID, position)
+			}
+			copied[layer][goodsID] = position
+		}
+	}
+	return planogram{positions: copied, radius: radius}, nil
+}
+
+// normalizeLayer 多传感器层未填写总重量时按各称重单元之和计算，称重单元有精确读数时一并计算精确总重量
+func normalizeLayer(layer model.Layer) model.Layer {
+	if len(layer.Cells) == 0 || layer.Weight != 0 || layer.Precise != 0 {
+		return layer
+	}
+	var total model.Weight
+	for _, cell := range layer.Cells {
+		total += cell.Reading()
+	}
+	layer.Weight = total.RoundGrams()
+	layer.Precise = total
+	return layer
+}
+
+// localize 根据各称重单元的重量减少估计取货位置，返回变化的重心
+// 称重单元数量不一致或减少的重量不超过传感器容差时无法定位
+func localize(beginLayer, endLayer model.Layer, sensorTolerance model.Weight) (float64, bool) {
+	if len(beginLayer.Cells) < 2 || len(beginLayer.Cells) != len(endLayer.Cells) {
+		return 0, false
+	}
+
+	total := 0.0
+	moment := 0.0
+	for i, cell := range beginLayer.Cells {
+		delta := (cell.Reading() - endLayer.Cells[i].Reading()).Float()
+		total += delta
+		moment += delta * cell.Position
+	}
+	if total <= sensorTolerance.Float() {
+		return 0, false
+	}
+
+	return math.Min(math.Max(moment/total, 0), 1), true
+}
+
+// recognizeLanes 在变化位置附近的货道中识别，无法定位或没有货道规划时返回空
+func (wr *WeightRecognizer) recognizeLanes(ctx context.Context, snap *catalogSnapshot, beginLayer, endLayer model.Layer, tol layerTolerance, weightDiff int) ([]RecognitionItem, SearchStats) {
+	layer := beginLayer.Index
+	positions := snap.planogram.positions[layer]
+	if len(positions) == 0 || len(snap.layerWeighed[layer]) > 0 {
+		return nil, SearchStats{}
+	}
+	center, ok := localize(beginLayer, endLayer, wr.weight(tol.sensor))
+	if !ok {
+		return nil, SearchStats{}
+	}
+
+	layerGoods := snap.layerGoodsMap[layer]
+	nearest := math.Inf(1)
+	for _, good := range layerGoods {
+		if position, ok := positions[good.ID]; ok {
+			nearest = math.Min(nearest, math.Abs(position-center))
+		}
+	}
+
+	// 选出距变化位置在定位半径内的货道上的商品，保持按重量排序
+	costs := snap.layerCosts(layer)
+	laneGoods := make([]model.Goods, 0)
+	laneCosts := make([]float64, 0)
+	laneTol := layerTolerance{sensor: tol.sensor, maxRatio: tol.maxRatio}
+	for i, good := range layerGoods {
+		position, ok := positions[good.ID]
+		if !ok || math.Abs(position-center) > nearest+snap.planogram.radius {
+			continue
+		}
+		laneGoods = append(laneGoods, good)
+		laneCosts = append(laneCosts, costs[i])
+		laneTol.slacks = append(laneTol.slacks, tol.slacks[i])
+	}
+	if len(laneGoods) == 0 || len(laneGoods) == len(layerGoods) {
+		return nil, SearchStats{}
+	}
+
+	return wr.searchCombination(ctx, snap, laneGoods, laneCosts, layer, laneTol, weightDiff)
+}
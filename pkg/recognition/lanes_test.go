@@ -0,0 +1,125 @@
+package recognition
+
+import (
+	"VendingMachineWeightRecognition/pkg/exception"
+	"VendingMachineWeightRecognition/pkg/model"
+	"testing"
+)
+
+// twoCellLayer 返回左右两个称重单元的层读数
+func twoCellLayer(left, right int) []model.Layer {
+	return []model.Layer{{
+		Index: 1,
+		Cells: []model.Cell{
+			{Position: 0, Weight: left},
+			{Position: 1, Weight: right},
+		},
+	}}
+}
+
+// TestWeightRecognizer_LaneLocalization 测试根据称重单元的变化分布区分重量相近的商品
+func TestWeightRecognizer_LaneLocalization(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Weight: 500},
+		{ID: "000002", Weight: 502},
+	}
+
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 10},
+		{GoodsID: "000002", Layer: 1, Num: 10},
+	}
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance: 5,
+		Goods:           goods,
+		Stocks:          stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 没有货道规划时无法区分
+	result := recognizer.Recognize(twoCellLayer(3000, 3000), twoCellLayer(2550, 2949))
+	if len(result.Exceptions) != 1 || result.Exceptions[0].Exception != exception.RecognitionError {
+		t.Fatalf("没有货道规划时应该检测到识别异常，实际识别出%v", result.Items)
+	}
+
+	if err := recognizer.SetPlanogram(map[int]map[string]float64{
+		1: {"000001": 0.1, "000002": 0.9},
+	}, 0.2); err != nil {
+		t.Fatal(err)
+	}
+
+	// 变化集中在左侧
+	result = recognizer.Recognize(twoCellLayer(3000, 3000), twoCellLayer(2550, 2949))
+	if len(result.Items) != 1 || result.Items[0].GoodsID != "000001" || result.Items[0].Num != 1 {
+		t.Errorf("应该识别出左侧的商品1，实际识别出%v", result.Items)
+	}
+
+	// 变化集中在右侧
+	result = recognizer.Recognize(twoCellLayer(3000, 3000), twoCellLayer(2950, 2548))
+	if len(result.Items) != 1 || result.Items[0].GoodsID != "000002" || result.Items[0].Num != 1 {
+		t.Errorf("应该识别出右侧的商品2，实际识别出%v", result.Items)
+	}
+}
+
+// TestWeightRecognizer_LaneFallback 测试跨货道取货时回退到整层识别
+func TestWeightRecognizer_LaneFallback(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Weight: 300},
+		{ID: "000002", Weight: 700},
+	}
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 10},
+		{GoodsID: "000002", Layer: 1, Num: 10},
+	}
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance: 5,
+		Goods:           goods,
+		Stocks:          stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := recognizer.SetPlanogram(map[int]map[string]float64{
+		1: {"000001": 0, "000002": 1},
+	}, 0.1); err != nil {
+		t.Fatal(err)
+	}
+
+	// 两端各取1件，重心偏向右侧
+	result := recognizer.Recognize(twoCellLayer(5000, 5000), twoCellLayer(4700, 4300))
+	if len(result.Items) != 2 {
+		t.Errorf("应该识别出两种商品各1个，实际识别出%v", result.Items)
+	}
+}
+
+// TestWeightRecognizer_SetPlanogramValidate 测试拒绝不合理的货道规划
+func TestWeightRecognizer_SetPlanogramValidate(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Weight: 500},
+		{ID: "000002", Weight: 502},
+	}
+
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 10},
+		{GoodsID: "000002", Layer: 1, Num: 10},
+	}
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance: 5,
+		Goods:           goods,
+		Stocks:          stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := recognizer.SetPlanogram(map[int]map[string]float64{1: {"000001": 1.5}}, 0.1); err == nil {
+		t.Error("超出范围的位置应该被拒绝")
+	}
+	if err := recognizer.SetPlanogram(nil, -1); err == nil {
+		t.Error("负的定位半径应该被拒绝")
+	}
+}
@@ -0,0 +1,206 @@
+package recognition
+
+import (
+	"VendingMachineWeightRecognition/pkg/model"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// LearnedWeight 商品实际单件重量的统计
+type LearnedWeight struct {
+	GoodsID       string
+	CatalogWeight model.Weight // 商品目录中当前生效的单件重量，优先使用精确重量
+	Mean          model.Weight // 观测到的单件重量均值，精确到 mg
+	StdDev        model.Weight // 观测到的单件重量标准差
+	Samples       int          // 观测次数
+	Deviated      bool         // 均值是否偏离目录重量超过阈值
+}
+
+// weightStats 单个商品的在线均值方差（Welford 算法），单位 g
+type weightStats struct {
+	count int
+	mean  float64
+	m2    float64
+}
+
+// add 加入一次观测
+func (ws *weightStats) add(x float64) {
+	ws.count++
+	delta := x - ws.mean
+	ws.mean += delta / float64(ws.count)
+	ws.m2 += delta * (x - ws.mean)
+}
+
+// stdDev 返回样本标准差
+func (ws *weightStats) stdDev() float64 {
+	if ws.count < 2 {
+		return 0
+	}
+	return math.Sqrt(ws.m2 / float64(ws.count-1))
+}
+
+// WeightLearner 根据确认的销售在线学习商品的实际单件重量，可被多个 goroutine 并发使用
+type WeightLearner struct {
+	deviationThreshold float64 // 偏离阈值，目录重量的百分比
+	minSamples         int     // 判断偏离和应用学习结果所需的最少观测次数
+
+	mu           sync.Mutex
+	catalog      map[string]model.Weight // 商品编号到目录重量的映射
+	singleLayers map[int]string          // 只有一种商品的层到该商品的映射
+	stats        map[string]*weightStats
+}
+
+// NewWeightLearner 创建重量学习器
+// deviationThreshold 为目录重量的百分比，minSamples 为判断偏离所需的最少观测次数
+func NewWeightLearner(goods []model.Goods, stocks []model.Stock, deviationThreshold float64, minSamples int) *WeightLearner {
+	wl := &WeightLearner{
+		deviationThreshold: deviationThreshold,
+		minSamples:         minSamples,
+		stats:              make(map[string]*weightStats),
+	}
+	wl.UpdateCatalog(goods, stocks)
+
+	return wl
+}
+
+// UpdateCatalog 更新商品目录和货道规划，已学习的统计保留
+func (wl *WeightLearner) UpdateCatalog(goods []model.Goods, stocks []model.Stock) {
+	now := time.Now()
+	catalog := make(map[string]model.Weight, len(goods))
+	for _, good := range goods {
+		catalog[good.ID] = good.At(now).UnitWeight()
+	}
+
+	layerGoods := make(map[int]map[string]bool)
+	for _, stock := range stocks {
+		if _, exists := layerGoods[stock.Layer]; !exists {
+			layerGoods[stock.Layer] = make(map[string]bool)
+		}
+		layerGoods[stock.Layer][stock.GoodsID] = true
+	}
+	singleLayers := make(map[int]string)
+	for layer, ids := range layerGoods {
+		if len(ids) != 1 {
+			continue
+		}
+		for id := range ids {
+			singleLayers[layer] = id
+		}
+	}
+
+	wl.mu.Lock()
+	defer wl.mu.Unlock()
+
+	wl.catalog = catalog
+	wl.singleLayers = singleLayers
+}
+
+// ObserveResult 从识别结果中学习，只采用单商品层的结果
+// 多商品层的组合可能识别错误，需要经人工确认后通过 ObserveConfirmed 加入
+// 重量差由整机重量推算或组合搜索提前结束的层不可信，同样跳过
+func (wl *WeightLearner) ObserveResult(result RecognitionResult) {
+	wl.mu.Lock()
+	defer wl.mu.Unlock()
+
+	for _, layer := range result.Layers {
+		goodsID, single := wl.singleLayers[layer.Layer]
+		if !single || len(layer.Items) != 1 || layer.Items[0].GoodsID != goodsID {
+			continue
+		}
+		if layer.Inferred || layer.Search.Truncated {
+			continue
+		}
+		if layer.Items[0].Weight > 0 || layer.Items[0].Returned > 0 {
+			continue // 称重商品没有固定单件重量，部分饮用的商品重量不完整
+		}
+		wl.observe(goodsID, layer.Items[0].Num, layer.Diff)
+	}
+}
+
+// ObserveConfirmed 加入一次人工确认的销售：某层减少 weightDiff，对应 num 件 goodsID
+func (wl *WeightLearner) ObserveConfirmed(goodsID string, num int, weightDiff model.Weight) {
+	wl.mu.Lock()
+	defer wl.mu.Unlock()
+
+	wl.observe(goodsID, num, weightDiff)
+}
+
+// observe 记录一次观测，调用方需持有锁
+func (wl *WeightLearner) observe(goodsID string, num int, weightDiff model.Weight) {
+	if num <= 0 || weightDiff <= 0 {
+		return
+	}
+	if _, exists := wl.stats[goodsID]; !exists {
+		wl.stats[goodsID] = &weightStats{}
+	}
+	wl.stats[goodsID].add(weightDiff.Float() / float64(num))
+}
+
+// Learned 返回单个商品的学习结果
+func (wl *WeightLearner) Learned(goodsID string) (LearnedWeight, bool) {
+	wl.mu.Lock()
+	defer wl.mu.Unlock()
+
+	ws, exists := wl.stats[goodsID]
+	if !exists {
+		return LearnedWeight{}, false
+	}
+	return wl.learned(goodsID, ws), true
+}
+
+// Deviations 返回观测次数足够且偏离目录重量的商品，按商品编号排序
+func (wl *WeightLearner) Deviations() []LearnedWeight {
+	wl.mu.Lock()
+	defer wl.mu.Unlock()
+
+	deviations := make([]LearnedWeight, 0)
+	for goodsID, ws := range wl.stats {
+		if learned := wl.learned(goodsID, ws); learned.Deviated {
+			deviations = append(deviations, learned)
+		}
+	}
+	sort.Slice(deviations, func(i, j int) bool {
+		return deviations[i].GoodsID < deviations[j].GoodsID
+	})
+	return deviations
+}
+
+// learned 生成学习结果，调用方需持有锁
+func (wl *WeightLearner) learned(goodsID string, ws *weightStats) LearnedWeight {
+	catalogWeight := wl.catalog[goodsID]
+	learned := LearnedWeight{
+		GoodsID:       goodsID,
+		CatalogWeight: catalogWeight,
+		Mean:          model.GramsFloat(ws.mean),
+		StdDev:        model.GramsFloat(ws.stdDev()),
+		Samples:       ws.count,
+	}
+	if ws.count >= wl.minSamples && catalogWeight > 0 {
+		learned.Deviated = math.Abs(ws.mean-catalogWeight.Float()) > catalogWeight.Float()*wl.deviationThreshold/100
+	}
+	return learned
+}
+
+// Apply 将观测次数足够的商品的学习重量写入识别器，返回实际更新的商品
+func (wl *WeightLearner) Apply(wr *WeightRecognizer) []LearnedWeight {
+	wl.mu.Lock()
+	applied := make([]LearnedWeight, 0)
+	weights := make(map[string]model.Weight)
+	for goodsID, ws := range wl.stats {
+		if ws.count < wl.minSamples {
+			continue
+		}
+		applied = append(applied, wl.learned(goodsID, ws))
+		weights[goodsID] = model.GramsFloat(ws.mean)
+	}
+	wl.mu.Unlock()
+
+	sort.Slice(applied, func(i, j int) bool {
+		return applied[i].GoodsID < applied[j].GoodsID
+	})
+	wr.UpdateGoodsWeights(weights)
+
+	return applied
+}
@@ -0,0 +1,165 @@
+package recognition
+
+import (
+	"VendingMachineWeightRecognition/pkg/model"
+	"math"
+	"testing"
+)
+
+// TestWeightLearner_ObserveResult 测试从单商品层的识别结果中学习
+func TestWeightLearner_ObserveResult(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Weight: 100},
+		{ID: "000002", Weight: 200},
+		{ID: "000003", Weight: 500},
+	}
+
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 10},
+		{GoodsID: "000002", Layer: 2, Num: 10},
+		{GoodsID: "000003", Layer: 2, Num: 10},
+	}
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance:  10,
+		PackageTolerance: PercentTolerance(10),
+		Goods:            goods,
+		Stocks:           stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	learner := NewWeightLearner(goods, stocks, 5.0, 3)
+
+	// 商品1实际重量约108g，第2层是多商品层，不参与学习
+	for _, endWeight := range []int{892, 891, 893, 784} {
+		result := recognizer.Recognize(
+			[]model.Layer{{Index: 1, Weight: 1000}, {Index: 2, Weight: 3000}},
+			[]model.Layer{{Index: 1, Weight: endWeight}, {Index: 2, Weight: 2800}},
+		)
+		learner.ObserveResult(result)
+	}
+
+	learned, ok := learner.Learned("000001")
+	if !ok || learned.Samples != 4 {
+		t.Fatalf("商品1应该有4次观测，实际为%+v", learned)
+	}
+	if math.Abs(learned.Mean.Float()-108) > 0.5 {
+		t.Errorf("商品1的学习重量应该约为108g，实际为%v", learned.Mean)
+	}
+	if !learned.Deviated {
+		t.Error("商品1偏离目录重量超过5%，应该被标记")
+	}
+	if _, ok := learner.Learned("000002"); ok {
+		t.Error("多商品层的识别结果不应该参与学习")
+	}
+
+	deviations := learner.Deviations()
+	if len(deviations) != 1 || deviations[0].GoodsID != "000001" {
+		t.Errorf("应该只有商品1偏离，实际为%v", deviations)
+	}
+
+	// 推算的重量差和提前结束的搜索不参与学习
+	items := []RecognitionItem{{GoodsID: "000001", Num: 1}}
+	learner.ObserveResult(RecognitionResult{Layers: []LayerResult{
+		{Layer: 1, Diff: model.Grams(150), Items: items, Inferred: true},
+		{Layer: 1, Diff: model.Grams(150), Items: items, Search: SearchStats{Truncated: true}},
+	}})
+	if learned, _ := learner.Learned("000001"); learned.Samples != 4 {
+		t.Errorf("推算或提前结束的层不应该参与学习，实际为%+v", learned)
+	}
+}
+
+// TestWeightLearner_Apply 测试将学习结果写入识别器
+func TestWeightLearner_Apply(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Weight: 100},
+	}
+
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 20},
+	}
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance: 5,
+		Goods:           goods,
+		Stocks:          stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	learner := NewWeightLearner(goods, stocks, 5.0, 2)
+
+	// 人工确认的销售：实际单件重量为120g
+	learner.ObserveConfirmed("000001", 1, model.Grams(120))
+	learner.ObserveConfirmed("000001", 2, model.Grams(240))
+
+	// 按目录重量会把5件共600g识别为6件
+	beginLayers := []model.Layer{{Index: 1, Weight: 2000}}
+	endLayers := []model.Layer{{Index: 1, Weight: 1400}}
+	result := recognizer.Recognize(beginLayers, endLayers)
+	if len(result.Items) != 1 || result.Items[0].Num != 6 {
+		t.Fatalf("按目录重量应该识别为6件，实际为%v", result.Items)
+	}
+
+	applied := learner.Apply(recognizer)
+	if len(applied) != 1 || applied[0].GoodsID != "000001" {
+		t.Fatalf("应该应用商品1的学习结果，实际为%v", applied)
+	}
+	if recognizer.Goods()[0].Weight != 120 {
+		t.Errorf("识别器中商品1的重量应该更新为120g，实际为%d", recognizer.Goods()[0].Weight)
+	}
+
+	result = recognizer.Recognize(beginLayers, endLayers)
+	if len(result.Items) != 1 || result.Items[0].Num != 5 {
+		t.Errorf("按学习重量应该识别为5件，实际为%v", result.Items)
+	}
+}
+
+// TestWeightLearner_PreciseWeight 测试克以下精度的商品按精确重量学习和应用
+func TestWeightLearner_PreciseWeight(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Precise: model.GramsFloat(3.2)},
+	}
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 50},
+	}
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		Resolution:    100 * model.Milligram,
+		PreciseSensor: model.GramsFloat(0.2),
+		Goods:         goods,
+		Stocks:        stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	learner := NewWeightLearner(goods, stocks, 2.0, 5)
+
+	// 实际单件重量为3.3g
+	for i := 0; i < 5; i++ {
+		result := recognizer.Recognize(
+			[]model.Layer{{Index: 1, Precise: model.GramsFloat(200)}},
+			[]model.Layer{{Index: 1, Precise: model.GramsFloat(196.7)}},
+		)
+		learner.ObserveResult(result)
+	}
+
+	learned, ok := learner.Learned("000001")
+	if !ok || learned.Mean != model.GramsFloat(3.3) || learned.CatalogWeight != model.GramsFloat(3.2) || !learned.Deviated {
+		t.Fatalf("应该学习到3.3g并偏离目录重量3.2g，实际为%+v", learned)
+	}
+
+	learner.Apply(recognizer)
+	if good := recognizer.Goods()[0]; good.UnitWeight() != model.GramsFloat(3.3) {
+		t.Errorf("应该写入精确重量3.3g，实际为%v", good.UnitWeight())
+	}
+
+	result := recognizer.Recognize(
+		[]model.Layer{{Index: 1, Precise: model.GramsFloat(200)}},
+		[]model.Layer{{Index: 1, Precise: model.GramsFloat(193.4)}},
+	)
+	if len(result.Items) != 1 || result.Items[0].Num != 2 {
+		t.Errorf("按学习重量应该识别出2件，实际为%v", result.Items)
+	}
+}
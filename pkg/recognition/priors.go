@@ -0,0 +1,82 @@
+package recognition
+
+import (
+	"VendingMachineWeightRecognition/pkg/model"
+	"math"
+)
+
+// minPriorShare 单个商品的最小先验占比，避免没有销售记录的商品永远无法被识别
+const minPriorShare = 0.01
+
+// priorCosts 计算层上各商品的单件先验代价 -log(p)
+// p 为该商品在层上的购买占比，layerPriors 为空时各商品占比相同
+// 组合的先验代价为各件商品代价之和，件数越多代价越大，没有先验时自然优先件数少的组合
+func priorCosts(goods []model.Goods, layerPriors map[string]float64) []float64 {
+	costs := make([]float64, len(goods))
+
+	total := 0.0
+	for _, good := range goods {
+		total += math.Max(layerPriors[good.ID], 0)
+	}
+	if total <= 0 {
+		for i := range costs {
+			costs[i] = math.Log(float64(len(goods)))
+		}
+		return costs
+	}
+
+	// 占比设置下限后重新归一化
+	shares := make([]float64, len(goods))
+	sum := 0.0
+	for i, good := range goods {
+		shares[i] = math.Max(math.Max(layerPriors[good.ID], 0)/total, minPriorShare)
+		sum += shares[i]
+	}
+	for i := range shares {
+		costs[i] = -math.Log(shares[i] / sum)
+	}
+
+	return costs
+}
+
+// layerCosts 返回层上各商品的单件先验代价，与 layerGoodsMap 中的商品一一对应
+func (snap *catalogSnapshot) layerCosts(layer int) []float64 {
+	if costs, ok := snap.layerCostMap[layer]; ok {
+		return costs
+	}
+	return priorCosts(snap.layerGoodsMap[layer], nil)
+}
+
+// SetPriors 设置各层商品的购买先验，键为层号和商品编号，值为非负的相对购买频率
+// 多个组合都能解释重量差时，按先验和似然计算的后验概率选择，先验悬殊时件数较多的组合也可能胜出
+func (wr *WeightRecognizer) SetPriors(priors map[int]map[string]float64) {
+	copied := make(map[int]map[string]float64, len(priors))
+	for layer, layerPriors := range priors {
+		copied[layer] = make(map[string]float64, len(layerPriors))
+		for goodsID, prior := range layerPriors {
+			copied[layer][goodsID] = prior
+		}
+	}
+
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+
+	current := wr.state.Load()
+	wr.state.Store(newCatalogSnapshot(current.catalog, current.stocks, copied, current, wr.resolution))
+}
+
+// PriorsFromSales 根据历史识别结果统计各层商品的购买件数，结果可直接用于 SetPriors
+func PriorsFromSales(results []RecognitionResult) map[int]map[string]float64 {
+	priors := make(map[int]map[string]float64)
+	for _, result := range results {
+		for _, layer := range result.Layers {
+			for _, item := range layer.Items {
+				if _, exists := priors[layer.Layer]; !exists {
+					priors[layer.Layer] = make(map[string]float64)
+				}
+				priors[layer.Layer][item.GoodsID] += float64(item.Num)
+			}
+		}
+	}
+	return priors
+}
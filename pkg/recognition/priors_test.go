@@ -0,0 +1,216 @@
+package recognition
+
+import (
+	"VendingMachineWeightRecognition/pkg/exception"
+	"VendingMachineWeightRecognition/pkg/model"
+	"context"
+	"testing"
+)
+
+// TestWeightRecognizer_PriorsBreakTie 测试先验打破件数和重量都相同的平局
+func TestWeightRecognizer_PriorsBreakTie(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Weight: 100},
+		{ID: "000002", Weight: 300},
+		{ID: "000003", Weight: 200},
+	}
+
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 10},
+		{GoodsID: "000002", Layer: 1, Num: 10},
+		{GoodsID: "000003", Layer: 1, Num: 10},
+	}
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance: 10,
+		Goods:           goods,
+		Stocks:          stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	beginLayers := []model.Layer{{Index: 1, Weight: 3000}}
+	endLayers := []model.Layer{{Index: 1, Weight: 2600}}
+
+	result := recognizer.Recognize(beginLayers, endLayers)
+	if len(result.Exceptions) != 1 || result.Exceptions[0].Exception != exception.RecognitionError {
+		t.Fatalf("没有先验时应该检测到识别异常，实际结果%v", result.Exceptions)
+	}
+
+	// 该层主要卖出商品3
+	recognizer.SetPriors(map[int]map[string]float64{
+		1: {"000001": 2, "000002": 1, "000003": 40},
+	})
+
+	result = recognizer.Recognize(beginLayers, endLayers)
+	if len(result.Items) != 1 || result.Items[0].GoodsID != "000003" || result.Items[0].Num != 2 {
+		t.Errorf("应该识别出2个商品3，实际识别出%v", result.Items)
+	}
+
+	// 分支定界搜索得到相同结果
+	snap := recognizer.state.Load()
+	items, _ := recognizer.findBestCombination(context.Background(), snap, snap.layerGoodsMap[1], 1, recognizer.resolveTolerance(snap, 1), 400)
+	if len(items) != 1 || items[0].GoodsID != "000003" || items[0].Num != 2 {
+		t.Errorf("分支定界应该识别出2个商品3，实际识别出%v", items)
+	}
+}
+
+// TestWeightRecognizer_PriorsAcrossItemCounts 测试后验概率在不同件数的组合之间比较
+func TestWeightRecognizer_PriorsAcrossItemCounts(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Weight: 250},
+		{ID: "000002", Weight: 500},
+	}
+
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 10},
+		{GoodsID: "000002", Layer: 1, Num: 10},
+	}
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance: 5,
+		Goods:           goods,
+		Stocks:          stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	beginLayers := []model.Layer{{Index: 1, Weight: 5000}}
+	endLayers := []model.Layer{{Index: 1, Weight: 4500}}
+
+	// 没有先验时件数少的组合先验概率更高
+	result := recognizer.Recognize(beginLayers, endLayers)
+	if len(result.Items) != 1 || result.Items[0].GoodsID != "000002" || result.Items[0].Num != 1 {
+		t.Errorf("没有先验时应该识别出1个商品2，实际识别出%v", result.Items)
+	}
+
+	// 该层几乎只卖出商品1
+	recognizer.SetPriors(map[int]map[string]float64{
+		1: {"000001": 1000, "000002": 1},
+	})
+
+	result = recognizer.Recognize(beginLayers, endLayers)
+	if len(result.Items) != 1 || result.Items[0].GoodsID != "000001" || result.Items[0].Num != 2 {
+		t.Errorf("应该识别出2个商品1，实际识别出%v", result.Items)
+	}
+
+	// 分支定界搜索得到相同结果
+	snap := recognizer.state.Load()
+	items, _ := recognizer.findBestCombination(context.Background(), snap, snap.layerGoodsMap[1], 1, recognizer.resolveTolerance(snap, 1), 500)
+	if len(items) != 1 || items[0].GoodsID != "000001" || items[0].Num != 2 {
+		t.Errorf("分支定界应该识别出2个商品1，实际识别出%v", items)
+	}
+}
+
+// TestWeightRecognizer_PriorsSeparateSameWeight 测试先验不同时可以区分相同重量的商品
+func TestWeightRecognizer_PriorsSeparateSameWeight(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Weight: 500},
+		{ID: "000002", Weight: 500},
+	}
+
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 10},
+		{GoodsID: "000002", Layer: 1, Num: 10},
+	}
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance: 5,
+		Goods:           goods,
+		Stocks:          stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	beginLayers := []model.Layer{{Index: 1, Weight: 5000}}
+	endLayers := []model.Layer{{Index: 1, Weight: 4500}}
+
+	result := recognizer.Recognize(beginLayers, endLayers)
+	if len(result.Exceptions) != 1 || result.Exceptions[0].Exception != exception.RecognitionError {
+		t.Fatalf("没有先验时相同重量的商品无法区分，实际结果%v", result.Exceptions)
+	}
+
+	recognizer.SetPriors(map[int]map[string]float64{
+		1: {"000001": 1, "000002": 20},
+	})
+
+	result = recognizer.Recognize(beginLayers, endLayers)
+	if len(result.Items) != 1 || result.Items[0].GoodsID != "000002" || result.Items[0].Num != 1 {
+		t.Errorf("应该按先验识别出1个商品2，实际识别出%v", result.Items)
+	}
+}
+
+// TestPriorsFromSales 测试从历史识别结果统计先验
+func TestPriorsFromSales(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Weight: 100},
+		{ID: "000002", Weight: 300},
+		{ID: "000003", Weight: 200},
+	}
+
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 10},
+		{GoodsID: "000002", Layer: 1, Num: 10},
+		{GoodsID: "000003", Layer: 1, Num: 10},
+	}
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance: 10,
+		Goods:           goods,
+		Stocks:          stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	history := []RecognitionResult{
+		recognizer.Recognize([]model.Layer{{Index: 1, Weight: 3000}}, []model.Layer{{Index: 1, Weight: 2800}}),
+		recognizer.Recognize([]model.Layer{{Index: 1, Weight: 3000}}, []model.Layer{{Index: 1, Weight: 2800}}),
+		recognizer.Recognize([]model.Layer{{Index: 1, Weight: 3000}}, []model.Layer{{Index: 1, Weight: 2900}}),
+	}
+
+	priors := PriorsFromSales(history)
+	if priors[1]["000003"] != 2 || priors[1]["000001"] != 1 {
+		t.Errorf("先验统计错误：%v", priors)
+	}
+
+	recognizer.SetPriors(priors)
+	result := recognizer.Recognize([]model.Layer{{Index: 1, Weight: 3000}}, []model.Layer{{Index: 1, Weight: 2600}})
+	if len(result.Items) != 1 || result.Items[0].GoodsID != "000003" {
+		t.Errorf("应该按历史销售识别出商品3，实际识别出%v", result.Items)
+	}
+}
+
+// TestWeightRecognizer_PosteriorNormalization 测试容差大的组合不会因残差被摊薄而压过同样吻合的精确组合
+func TestWeightRecognizer_PosteriorNormalization(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Weight: 100},
+		{ID: "000002", Weight: 200},
+		{ID: "000003", Weight: 120},
+		{ID: "000004", Weight: 180},
+	}
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 10},
+		{GoodsID: "000002", Layer: 1, Num: 10},
+		{GoodsID: "000003", Layer: 1, Num: 10},
+		{GoodsID: "000004", Layer: 1, Num: 10},
+	}
+	recognizer, err := NewWeightRecognizerWithOptions(Options{SensorTolerance: 5, Goods: goods, Stocks: stocks})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := recognizer.SetToleranceOverrides(ToleranceOverrides{
+		GoodsPackage: map[string]Tolerance{"000001": GramsTolerance(30)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 100+200 和 120+180 都偏差3g，前者的允许偏差为35g，后者只有5g
+	result := recognizer.Recognize([]model.Layer{{Index: 1, Weight: 3000}}, []model.Layer{{Index: 1, Weight: 2697}})
+	if len(result.Items) != 2 || result.Items[0].GoodsID == "000001" || result.Items[1].GoodsID == "000001" {
+		t.Errorf("应该识别出商品3和商品4各1个，实际识别出%v", result.Items)
+	}
+}
@@ -0,0 +1,161 @@
+package recognition
+
+import (
+	"VendingMachineWeightRecognition/pkg/exception"
+	"VendingMachineWeightRecognition/pkg/model"
+	"context"
+	"testing"
+)
+
+// TestWeightRecognizer_SubGramResolution 测试按 0.1g 精度区分轻量商品
+func TestWeightRecognizer_SubGramResolution(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Precise: model.GramsFloat(3.2)},
+		{ID: "000002", Precise: model.GramsFloat(4.5)},
+	}
+
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 20},
+		{GoodsID: "000002", Layer: 1, Num: 20},
+	}
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		Resolution:    100 * model.Milligram,
+		PreciseSensor: model.GramsFloat(0.2),
+		Goods:         goods,
+		Stocks:        stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	begin := []model.Layer{{Index: 1, Precise: model.GramsFloat(500)}}
+	for _, tc := range []struct {
+		end     float64
+		goodsID string
+	}{
+		{496.8, "000001"},
+		{495.5, "000002"},
+	} {
+		result := recognizer.Recognize(begin, []model.Layer{{Index: 1, Precise: model.GramsFloat(tc.end)}})
+		if len(result.Items) != 1 || result.Items[0].GoodsID != tc.goodsID || result.Items[0].Num != 1 {
+			t.Errorf("%.1fg 应该识别出1个商品%s，实际识别出%v", tc.end, tc.goodsID, result.Items)
+		}
+		if len(result.Layers) != 1 || result.Layers[0].Diff != model.GramsFloat(500-tc.end) {
+			t.Errorf("精确重量差错误：%+v", result.Layers)
+		}
+	}
+
+	// 7.7g = 3.2g + 4.5g
+	result := recognizer.Recognize(begin, []model.Layer{{Index: 1, Precise: model.GramsFloat(492.3)}})
+	if len(result.Items) != 2 {
+		t.Errorf("应该识别出两种商品各1个，实际识别出%v", result.Items)
+	}
+
+	// 超出容差
+	result = recognizer.Recognize(begin, []model.Layer{{Index: 1, Precise: model.GramsFloat(496)}})
+	if len(result.Exceptions) != 1 || result.Exceptions[0].Exception != exception.RecognitionError {
+		t.Errorf("4.0g 不应该被识别，实际识别出%v", result.Items)
+	}
+}
+
+// TestWeightRecognizer_SubGramCellsAndCabinet 测试多传感器层和整机重量使用精确读数
+func TestWeightRecognizer_SubGramCellsAndCabinet(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Precise: model.GramsFloat(3.2)},
+		{ID: "000002", Precise: model.GramsFloat(4.5)},
+	}
+
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 20},
+		{GoodsID: "000002", Layer: 1, Num: 20},
+	}
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		Resolution:    100 * model.Milligram,
+		PreciseSensor: model.GramsFloat(0.2),
+		Goods:         goods,
+		Stocks:        stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cells := func(left, right float64) []model.Layer {
+		return []model.Layer{{Index: 1, Cells: []model.Cell{
+			{Position: 0, Precise: model.GramsFloat(left)},
+			{Position: 1, Precise: model.GramsFloat(right)},
+		}}}
+	}
+
+	// 各称重单元之和为 3.2g 的减少，与整机重量一致
+	result := recognizer.RecognizeSession(context.Background(), model.Session{
+		BeginLayers: cells(250.4, 250.3),
+		EndLayers:   cells(250.4, 247.1),
+		Cabinet:     &model.CabinetWeight{BeginPrecise: model.GramsFloat(1000.7), EndPrecise: model.GramsFloat(997.5)},
+	})
+	if len(result.Exceptions) != 0 || len(result.Items) != 1 || result.Items[0].GoodsID != "000001" {
+		t.Errorf("应该按精确读数识别出1个商品1，实际结果%v %v", result.Items, result.Exceptions)
+	}
+	if len(result.Layers) != 1 || result.Layers[0].Diff != model.GramsFloat(3.2) {
+		t.Errorf("精确重量差错误：%+v", result.Layers)
+	}
+
+	// 称重单元超量程时按精确的整机重量推算
+	result = recognizer.RecognizeSession(context.Background(), model.Session{
+		BeginLayers: cells(250.4, -0.5),
+		EndLayers:   cells(250.4, -0.5),
+		Cabinet:     &model.CabinetWeight{BeginPrecise: model.GramsFloat(1000.7), EndPrecise: model.GramsFloat(996.2)},
+	})
+	if !result.Degraded || len(result.Items) != 1 || result.Items[0].GoodsID != "000002" {
+		t.Errorf("应该按整机重量推算出1个商品2，实际结果%v %v", result.Items, result.Exceptions)
+	}
+}
+
+// TestWeightRecognizer_GramCallersUnchanged 测试整数克的调用方式在高精度下结果不变
+func TestWeightRecognizer_GramCallersUnchanged(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Weight: 100},
+		{ID: "000002", Weight: 250},
+	}
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 10},
+		{GoodsID: "000002", Layer: 1, Num: 10},
+	}
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		Resolution:       100 * model.Milligram,
+		SensorTolerance:  5,
+		PackageTolerance: GramsTolerance(2),
+		Goods:            goods,
+		Stocks:           stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := recognizer.Recognize([]model.Layer{{Index: 1, Weight: 3000}}, []model.Layer{{Index: 1, Weight: 2548}})
+	if len(result.Items) != 2 || result.Layers[0].WeightDiff != 452 {
+		t.Errorf("应该识别出2个商品1和1个商品2，实际识别出%v", result.Items)
+	}
+}
+
+// TestNewWeightRecognizerWithOptions_Resolution 测试拒绝不合理的精度和传感器容差
+func TestNewWeightRecognizerWithOptions_Resolution(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Precise: model.GramsFloat(3.2)},
+		{ID: "000002", Precise: model.GramsFloat(4.5)},
+	}
+
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 20},
+		{GoodsID: "000002", Layer: 1, Num: 20},
+	}
+
+	if _, err := NewWeightRecognizerWithOptions(Options{Resolution: -1, Goods: goods, Stocks: stocks}); err == nil {
+		t.Error("负的精度应该被拒绝")
+	}
+	if _, err := NewWeightRecognizerWithOptions(Options{PreciseSensor: -model.Milligram, Goods: goods, Stocks: stocks}); err == nil {
+		t.Error("负的传感器容差应该被拒绝")
+	}
+}
@@ -1,11 +1,19 @@
 package recognition
 
-import "VendingMachineWeightRecognition/pkg/exception"
+import (
+	"VendingMachineWeightRecognition/pkg/exception"
+	"VendingMachineWeightRecognition/pkg/model"
+)
 
 // RecognitionItem 识别结果项
 type RecognitionItem struct {
-	GoodsID string
-	Num     int
+	GoodsID  string
+	Num      int
+	Weight   int // 称重商品实际取走的重量，单位 g，按件商品为 0
+	Consumed int // 部分饮用后放回的商品估计饮用量，单位 g，按比例计费时使用
+	Returned int // 其中部分饮用后放回货架的件数，商品仍在货架上，不扣减库存
+
+	Info model.GoodsInfo // 商品的名称、条码、价格等描述信息，不在目录中的商品为空
 }
 
 // RecognitionException 识别异常
@@ -14,6 +22,9 @@ type RecognitionException struct {
 	Exception   exception.ExceptionEnum
 	BeginWeight int
 	EndWeight   int
+	GoodsID     string        // 部分饮用异常对应的商品
+	Consumed    int           // 部分饮用异常的估计饮用量，单位 g
+	Swap        *SwapEvidence // 调包嫌疑异常的证据
 }
 
 // RecognitionResult 识别结果
@@ -21,4 +32,44 @@ type RecognitionResult struct {
 	Successful bool
 	Items      []RecognitionItem
 	Exceptions []RecognitionException
+	Layers     []LayerResult // 各层识别详情
+	Truncated  bool          // 是否有层的组合搜索因超时或取消而提前结束
+	Degraded   bool          // 是否有层的重量差由整机重量推算，结果置信度较低
+
+	SensorTolerances map[int]model.Weight // 本次识别各层实际使用的传感器容差
+}
+
+// LayerResult 单层识别详情
+type LayerResult struct {
+	Layer      int               // 层号
+	WeightDiff int               // 该层减少的重量，四舍五入到整数克
+	Diff       model.Weight      // 该层减少的重量，精确到识别精度
+	Items      []RecognitionItem // 该层识别出的商品
+	Search     SearchStats       // 该层组合搜索统计
+	Inferred   bool              // 重量差由整机重量推算
+}
+
+// SearchStats 组合搜索统计
+type SearchStats struct {
+	Explored  int64 // 已覆盖的组合数
+	Total     int64 // 搜索空间中的组合总数
+	Truncated bool  // 搜索是否因超时或取消而提前结束
+}
+
+// Coverage 返回已覆盖的搜索空间比例，范围 [0, 1]
+func (s SearchStats) Coverage() float64 {
+	if s.Total == 0 {
+		return 1
+	}
+	return float64(s.Explored) / float64(s.Total)
+}
+
+// Coverage 返回所有层合计的搜索空间覆盖比例
+func (r RecognitionResult) Coverage() float64 {
+	total := SearchStats{}
+	for _, layer := range r.Layers {
+		total.Explored += layer.Search.Explored
+		total.Total += layer.Search.Total
+	}
+	return total.Coverage()
 }
@@ -0,0 +1,257 @@
+package recognition
+
+import (
+	"VendingMachineWeightRecognition/pkg/model"
+	"context"
+	"math"
+)
+
+const (
+	ctxCheckInterval = 1024    // 分支定界搜索中检查 ctx 的节点间隔
+	maxSearchNodes   = 1 << 24 // 单层分支定界搜索的节点上限，保证无 ctx 时也能在有限时间内结束
+	scoreEpsilon     = 1e-9    // 比较先验代价和后验概率时视为相等的误差
+	weightEpsilon    = 1e-6    // 累加允许偏差时的浮点误差，单位 g
+)
+
+// weightWindow 名义重量的可行窗口
+// 组合可行当且仅当 |weightDiff - 名义重量| <= 传感器容差 + 组合中各件商品的允许偏差之和
+type weightWindow struct {
+	weightDiff int
+	tol        layerTolerance
+	lo         int // 任意组合可能可行的名义重量下界
+	hi         int // 任意组合可能可行的名义重量上界
+}
+
+// newWeightWindow 根据重量差和该层的有效容差计算可行窗口
+func newWeightWindow(weightDiff int, tol layerTolerance) weightWindow {
+	hi := math.MaxInt32
+	if tol.maxRatio < 1 {
+		hi = int(math.Floor(float64(weightDiff+tol.sensor) / (1 - tol.maxRatio)))
+	}
+
+	return weightWindow{
+		weightDiff: weightDiff,
+		tol:        tol,
+		lo:         max(int(math.Ceil(float64(weightDiff-tol.sensor)/(1+tol.maxRatio))), 1),
+		hi:         hi,
+	}
+}
+
+// accepts 判断名义重量为 w、允许偏差之和为 slack 的组合是否在容差范围内
+func (ww weightWindow) accepts(w int, slack float64) bool {
+	return float64(abs(ww.weightDiff-w)) <= float64(ww.tol.sensor)+slack+weightEpsilon
+}
+
+// possible 判断名义重量为 w 的组合是否可能在容差范围内
+func (ww weightWindow) possible(w int) bool {
+	return ww.accepts(w, float64(w)*ww.tol.maxRatio)
+}
+
+// logPosterior 返回名义重量为 w、允许偏差之和为 slack、先验代价为 cost 的组合的对数后验概率（忽略常数项）
+// 似然按正态分布计算，容差范围视为两倍标准差；包含归一化项 -log(sigma)，
+// 否则容差大的组合残差被摊薄，会压过容差小但同样吻合的组合
+func (ww weightWindow) logPosterior(w int, slack float64, cost float64) float64 {
+	sigma := (float64(ww.tol.sensor) + slack) / 2
+	if sigma <= 0 {
+		return -cost
+	}
+	d := float64(ww.weightDiff - w)
+	return -cost - math.Log(sigma) - d*d/(2*sigma*sigma)
+}
+
+// branchAndBound 单层分支定界搜索
+// 以每种商品的数量为变量，数量受库存限制，按部分重量和后验概率上界剪枝
+// 目标与索引一致：后验概率最大，最优解不唯一时视为无法识别
+type branchAndBound struct {
+	ctx     context.Context
+	goods   []model.Goods // 按重量从大到小排序，先尝试重的商品以尽早得到件数少的解
+	stocks  []int
+	costs   []float64 // 与 goods 对应的单件先验代价
+	slacks  []float64 // 与 goods 对应的单件允许偏差
+	window  weightWindow
+	suffix  []int     // suffix[i] 为第 i 个及之后商品全部取完的总重量
+	minCost []float64 // minCost[i] 为第 i 个及之后商品的最小单件先验代价
+	subtree []int64   // subtree[i] 为第 i 个及之后商品的取值组合数，饱和到 MaxInt64
+
+	current   []int
+	best      []int
+	found     bool
+	bestScore float64
+	ambiguous bool
+
+	nodes int64
+	stats SearchStats
+}
+
+// findBestCombination 使用分支定界查找最佳组合
+// 搜索完整结束时结果为可证明的最优解；ctx 结束或节点数超过上限时返回目前的最佳组合并标记 Truncated
+func (wr *WeightRecognizer) findBestCombination(ctx context.Context, snap *catalogSnapshot, goods []model.Goods, layer int, tol layerTolerance, targetWeight int) ([]RecognitionItem, SearchStats) {
+	return wr.searchCombination(ctx, snap, goods, snap.layerCosts(layer), layer, tol, targetWeight)
+}
+
+// searchCombination 在给定的商品范围内搜索，costs 和 tol.slacks 与 goods 一一对应
+func (wr *WeightRecognizer) searchCombination(ctx context.Context, snap *catalogSnapshot, goods []model.Goods, costs []float64, layer int, tol layerTolerance, targetWeight int) ([]RecognitionItem, SearchStats) {
+	n := len(goods)
+	bb := &branchAndBound{
+		ctx:     ctx,
+		goods:   make([]model.Goods, n),
+		stocks:  make([]int, n),
+		costs:   make([]float64, n),
+		slacks:  make([]float64, n),
+		window:  newWeightWindow(targetWeight, tol),
+		suffix:  make([]int, n+1),
+		minCost: make([]float64, n+1),
+		subtree: make([]int64, n+1),
+		current: make([]int, n),
+	}
+
+	for i := 0; i < n; i++ {
+		good := goods[n-1-i]
+		if good.Weight <= 0 {
+			return nil, SearchStats{}
+		}
+		bb.goods[i] = good
+		bb.stocks[i] = max(snap.layerStockMap[layer][good.ID], 0)
+		bb.costs[i] = costs[n-1-i]
+		bb.slacks[i] = tol.slacks[n-1-i]
+	}
+
+	bb.subtree[n] = 1
+	bb.minCost[n] = math.Inf(1)
+	for i := n - 1; i >= 0; i-- {
+		bb.suffix[i] = bb.suffix[i+1] + bb.stocks[i]*bb.goods[i].Weight
+		bb.minCost[i] = math.Min(bb.minCost[i+1], bb.costs[i])
+		bb.subtree[i] = saturatingMul(bb.subtree[i+1], int64(bb.stocks[i]+1))
+	}
+	bb.stats.Total = bb.subtree[0]
+
+	bb.search(0, 0, 0, 0)
+
+	if bb.stats.Truncated {
+		bb.stats.Explored = min64(bb.stats.Explored, bb.stats.Total-1)
+	} else {
+		bb.stats.Explored = bb.stats.Total
+	}
+
+	if bb.best == nil || bb.ambiguous {
+		return nil, bb.stats
+	}
+
+	items := make([]RecognitionItem, 0)
+	for i := n - 1; i >= 0; i-- {
+		if bb.best[i] > 0 {
+			items = append(items, RecognitionItem{
+				GoodsID: bb.goods[i].ID,
+				Num:     bb.best[i],
+			})
+		}
+	}
+	if bundleAmbiguous(snap, goods, layer, bb.window, items) {
+		return nil, bb.stats
+	}
+
+	return items, bb.stats
+}
+
+// search 从第 i 个商品开始搜索，weight、slack 和 cost 为已选商品的名义重量、允许偏差和先验代价
+func (bb *branchAndBound) search(i int, weight int, slack float64, cost float64) {
+	if bb.stats.Truncated {
+		return
+	}
+
+	// 定期检查是否超时、被取消或超过节点上限
+	if bb.nodes%ctxCheckInterval == 0 && (bb.ctx.Err() != nil || bb.nodes >= maxSearchNodes) {
+		bb.stats.Truncated = true
+		return
+	}
+	bb.nodes++
+
+	if i == len(bb.goods) {
+		bb.stats.Explored = saturatingAdd(bb.stats.Explored, 1)
+		if weight > 0 && bb.window.accepts(weight, slack) {
+			bb.consider(bb.window.logPosterior(weight, slack, cost))
+		}
+		return
+	}
+
+	// 剩余商品全部取完也达不到下界
+	if weight+bb.suffix[i] < bb.window.lo {
+		bb.prune(i)
+		return
+	}
+
+	// 后验概率上界：剩余商品中当前商品最重，至少还需要 ceil((lo-weight)/w) 件，每件代价不低于剩余商品的最小代价
+	// 允许偏差只增不减，归一化项 -log(sigma) 不超过当前值，似然的残差项不超过 0
+	if bb.found {
+		need := 0
+		if weight < bb.window.lo {
+			need = (bb.window.lo - weight + bb.goods[i].Weight - 1) / bb.goods[i].Weight
+		}
+		bound := -cost
+		if need > 0 {
+			bound -= float64(need) * bb.minCost[i]
+		}
+		if sigma := (float64(bb.window.tol.sensor) + slack) / 2; sigma > 0 {
+			bound -= math.Log(sigma)
+			if bound < bb.bestScore-scoreEpsilon {
+				bb.prune(i)
+				return
+			}
+		}
+	}
+
+	good := bb.goods[i]
+	limit := bb.stocks[i]
+	if room := (bb.window.hi - weight) / good.Weight; room < limit {
+		// 超出上界的取值无需搜索，直接计入已覆盖
+		bb.stats.Explored = saturatingAdd(bb.stats.Explored, saturatingMul(int64(limit-room), bb.subtree[i+1]))
+		limit = room
+	}
+
+	for q := limit; q >= 0; q-- {
+		bb.current[i] = q
+		bb.search(i+1, weight+q*good.Weight, slack+float64(q)*bb.slacks[i], cost+float64(q)*bb.costs[i])
+	}
+	bb.current[i] = 0
+}
+
+// consider 评估一个可行解
+func (bb *branchAndBound) consider(score float64) {
+	if !bb.found || score > bb.bestScore+scoreEpsilon {
+		bb.best = append(bb.best[:0], bb.current...)
+		bb.found = true
+		bb.bestScore = score
+		bb.ambiguous = false
+	} else if score >= bb.bestScore-scoreEpsilon {
+		bb.ambiguous = true
+	}
+}
+
+// prune 剪去第 i 个及之后商品的全部取值
+func (bb *branchAndBound) prune(i int) {
+	bb.stats.Explored = saturatingAdd(bb.stats.Explored, bb.subtree[i])
+}
+
+// saturatingAdd 饱和加法，结果不超过 MaxInt64
+func saturatingAdd(a, b int64) int64 {
+	if a > math.MaxInt64-b {
+		return math.MaxInt64
+	}
+	return a + b
+}
+
+// saturatingMul 饱和乘法，结果不超过 MaxInt64
+func saturatingMul(a, b int64) int64 {
+	if a != 0 && b > math.MaxInt64/a {
+		return math.MaxInt64
+	}
+	return a * b
+}
+
+// min64 返回两个 int64 中的较小值
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
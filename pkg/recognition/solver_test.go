@@ -0,0 +1,137 @@
+package recognition
+
+import (
+	"VendingMachineWeightRecognition/pkg/model"
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+)
+
+// sortedItems 按商品编号排序，便于比较
+func sortedItems(items []RecognitionItem) []RecognitionItem {
+	sorted := append([]RecognitionItem(nil), items...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].GoodsID < sorted[j].GoodsID
+	})
+	return sorted
+}
+
+// TestBranchAndBound_MatchesIndex 测试分支定界与索引的结果一致
+func TestBranchAndBound_MatchesIndex(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+
+	for round := 0; round < 200; round++ {
+		goods := make([]model.Goods, 0)
+		stocks := make([]model.Stock, 0)
+		used := make(map[int]bool)
+		for i := 0; i < 2+rng.Intn(4); i++ {
+			weight := 50 + rng.Intn(500)
+			if used[weight] {
+				continue
+			}
+			used[weight] = true
+			id := fmt.Sprintf("%06d", i+1)
+			goods = append(goods, model.Goods{ID: id, Weight: weight})
+			stocks = append(stocks, model.Stock{GoodsID: id, Layer: 1, Num: rng.Intn(6)})
+		}
+
+		recognizer, err := NewWeightRecognizerWithOptions(Options{
+			SensorTolerance:  rng.Intn(10),
+			PackageTolerance: PercentTolerance(float64(rng.Intn(6))),
+			Goods:            goods,
+			Stocks:           stocks,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		snap := recognizer.state.Load()
+		idx := snap.layerIndexMap[1]
+		if idx == nil {
+			continue
+		}
+
+		for k := 0; k < 20; k++ {
+			weightDiff := 20 + rng.Intn(2000)
+			expected, _, ok := idx.lookup(weightDiff, recognizer.resolveTolerance(snap, 1))
+			if !ok {
+				t.Fatalf("索引应该覆盖重量差%d", weightDiff)
+			}
+			actual, stats := recognizer.findBestCombination(context.Background(), snap, snap.layerGoodsMap[1], 1, recognizer.resolveTolerance(snap, 1), weightDiff)
+			if stats.Truncated {
+				t.Fatalf("小规模搜索不应该被截断")
+			}
+			if fmt.Sprint(sortedItems(expected)) != fmt.Sprint(sortedItems(actual)) {
+				t.Fatalf("重量差%d时结果不一致：索引%v，分支定界%v，商品%v，库存%v",
+					weightDiff, expected, actual, goods, stocks)
+			}
+		}
+	}
+}
+
+// TestBranchAndBound_WideLayer 测试宽货架上的分支定界搜索
+func TestBranchAndBound_WideLayer(t *testing.T) {
+	goods := make([]model.Goods, 0)
+	stocks := make([]model.Stock, 0)
+	for i := 0; i < 40; i++ {
+		id := fmt.Sprintf("%06d", i+1)
+		goods = append(goods, model.Goods{ID: id, Weight: 200 + i*53})
+		stocks = append(stocks, model.Stock{GoodsID: id, Layer: 1, Num: 10})
+	}
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance: 2,
+		Goods:           goods,
+		Stocks:          stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap := recognizer.state.Load()
+	heaviest := snap.layerGoodsMap[1][39]
+
+	start := time.Now()
+	items, stats := recognizer.findBestCombination(context.Background(), snap, snap.layerGoodsMap[1], 1, recognizer.resolveTolerance(snap, 1), heaviest.Weight*2)
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("宽货架搜索耗时过长：%v", elapsed)
+	}
+	if stats.Truncated || stats.Coverage() != 1 {
+		t.Errorf("搜索应该完整结束，实际统计%+v", stats)
+	}
+	if len(items) != 1 || items[0].GoodsID != heaviest.ID || items[0].Num != 2 {
+		t.Errorf("应该识别出2个商品%s，实际识别出%v", heaviest.ID, items)
+	}
+}
+
+// TestBranchAndBound_ProvesNoSolution 测试无解时完整搜索并返回空
+func TestBranchAndBound_ProvesNoSolution(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Weight: 300},
+		{ID: "000002", Weight: 500},
+	}
+
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 20},
+		{GoodsID: "000002", Layer: 1, Num: 20},
+	}
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		Goods:  goods,
+		Stocks: stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap := recognizer.state.Load()
+	items, stats := recognizer.findBestCombination(context.Background(), snap, snap.layerGoodsMap[1], 1, recognizer.resolveTolerance(snap, 1), 700)
+
+	if len(items) != 0 {
+		t.Errorf("700g无法由300g和500g组成，实际识别出%v", items)
+	}
+	if stats.Truncated || stats.Explored != stats.Total {
+		t.Errorf("无解时搜索应该完整覆盖，实际统计%+v", stats)
+	}
+}
@@ -0,0 +1,154 @@
+package recognition
+
+import (
+	"VendingMachineWeightRecognition/pkg/exception"
+	"VendingMachineWeightRecognition/pkg/model"
+	"math"
+	"sort"
+	"time"
+)
+
+// LayerEvent 从中间读数中提取的一次取放事件
+type LayerEvent struct {
+	Layer  int
+	Delta  model.Weight // 重量变化，负数表示取走，正数表示放回
+	Before model.Weight // 变化前的稳定读数
+	After  model.Weight // 变化后的稳定读数
+	Time   time.Time    // 读数稳定在新值的时间
+}
+
+// SwapEvidence 调包嫌疑的证据：取走一件商品后很快放回了等重的物品
+type SwapEvidence struct {
+	GoodsID string     // 取走重量对应的商品
+	Take    LayerEvent // 取走事件
+	PutBack LayerEvent // 放回事件
+}
+
+// Interval 返回取走到放回的间隔
+func (e SwapEvidence) Interval() time.Duration {
+	return e.PutBack.Time.Sub(e.Take.Time)
+}
+
+// LayerEvents 将中间读数按层提取为取放事件
+// 读数偏离当前稳定值超过传感器容差，且连续两次读数相差不超过容差时，认为重量稳定在新值
+// 手部按压等单次尖峰不会形成事件
+func (wr *WeightRecognizer) LayerEvents(samples []model.Sample) []LayerEvent {
+	return wr.layerEvents(wr.state.Load(), samples)
+}
+
+// layerEvents 按 snap 中的容差提取取放事件
+func (wr *WeightRecognizer) layerEvents(snap *catalogSnapshot, samples []model.Sample) []LayerEvent {
+	layers := make(map[int][]model.Sample)
+	for _, sample := range samples {
+		layers[sample.Layer] = append(layers[sample.Layer], sample)
+	}
+
+	events := make([]LayerEvent, 0)
+	for layer, layerSamples := range layers {
+		sort.SliceStable(layerSamples, func(i, j int) bool {
+			return layerSamples[i].Time.Before(layerSamples[j].Time)
+		})
+		sensor := wr.weight(wr.resolveTolerance(snap, layer).sensor)
+
+		stable := layerSamples[0].Reading()
+		for i := 1; i < len(layerSamples); i++ {
+			current := layerSamples[i].Reading()
+			if absWeight(current-stable) <= sensor || absWeight(current-layerSamples[i-1].Reading()) > sensor {
+				continue
+			}
+			events = append(events, LayerEvent{
+				Layer:  layer,
+				Delta:  current - stable,
+				Before: stable,
+				After:  current,
+				Time:   layerSamples[i].Time,
+			})
+			stable = current
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		if !events[i].Time.Equal(events[j].Time) {
+			return events[i].Time.Before(events[j].Time)
+		}
+		return events[i].Layer < events[j].Layer
+	})
+	return events
+}
+
+// DetectSwaps 检测疑似调包：某层取走一件商品后，在 maxInterval 内放回了重量相同的物品
+// 仅凭重量无法区分放回原商品与放回等重异物，结果只作为嫌疑上报，由人工核查
+func (wr *WeightRecognizer) DetectSwaps(samples []model.Sample, maxInterval time.Duration) []RecognitionException {
+	return wr.detectSwaps(wr.state.Load(), samples, maxInterval)
+}
+
+// detectSwaps 按 snap 中的商品目录和容差检测调包，会话识别时使用与各层识别相同的快照
+func (wr *WeightRecognizer) detectSwaps(snap *catalogSnapshot, samples []model.Sample, maxInterval time.Duration) []RecognitionException {
+	events := wr.layerEvents(snap, samples)
+
+	exceptions := make([]RecognitionException, 0)
+	for i, take := range events {
+		if take.Delta >= 0 {
+			continue
+		}
+		goodsID, ok := wr.matchSingleGoods(snap.at(take.Time, wr.resolution), take.Layer, wr.ticks(-take.Delta))
+		if !ok {
+			continue
+		}
+
+		sensor := wr.weight(wr.resolveTolerance(snap, take.Layer).sensor)
+		for _, putBack := range events[i+1:] {
+			if putBack.Layer != take.Layer {
+				continue
+			}
+			if putBack.Time.Sub(take.Time) > maxInterval {
+				break
+			}
+			if putBack.Delta < 0 {
+				break // 同层又发生取走，不再配对
+			}
+			// 两次变化各含两个读数的误差
+			if absWeight(putBack.Delta+take.Delta) > 2*sensor {
+				break
+			}
+
+			exceptions = append(exceptions, RecognitionException{
+				Layer:       take.Layer,
+				Exception:   exception.SwapSuspectedError,
+				BeginWeight: take.Before.RoundGrams(),
+				EndWeight:   putBack.After.RoundGrams(),
+				GoodsID:     goodsID,
+				Swap:        &SwapEvidence{GoodsID: goodsID, Take: take, PutBack: putBack},
+			})
+			break
+		}
+	}
+
+	return exceptions
+}
+
+// matchSingleGoods 查找单件重量与 weight 匹配的唯一商品，weight 以识别精度为单位
+func (wr *WeightRecognizer) matchSingleGoods(snap *catalogSnapshot, layer int, weight int) (string, bool) {
+	tol := wr.resolveTolerance(snap, layer)
+	goodsID := ""
+	matches := 0
+	for i, good := range snap.layerGoodsMap[layer] {
+		if snap.layerStockMap[layer][good.ID] <= 0 {
+			continue
+		}
+		if math.Abs(float64(weight-good.Weight)) > float64(2*tol.sensor)+tol.slacks[i]+weightEpsilon {
+			continue
+		}
+		goodsID = good.ID
+		matches++
+	}
+	return goodsID, matches == 1
+}
+
+// absWeight 返回重量的绝对值
+func absWeight(w model.Weight) model.Weight {
+	if w < 0 {
+		return -w
+	}
+	return w
+}
@@ -0,0 +1,108 @@
+package recognition
+
+import (
+	"VendingMachineWeightRecognition/pkg/exception"
+	"VendingMachineWeightRecognition/pkg/model"
+	"testing"
+	"time"
+)
+
+// samplesAt 按固定间隔生成某层的中间读数
+func samplesAt(layer int, start time.Time, interval time.Duration, weights ...int) []model.Sample {
+	samples := make([]model.Sample, len(weights))
+	for i, weight := range weights {
+		samples[i] = model.Sample{Layer: layer, Weight: weight, Time: start.Add(time.Duration(i) * interval)}
+	}
+	return samples
+}
+
+// TestWeightRecognizer_LayerEvents 测试从中间读数提取取放事件并忽略尖峰
+func TestWeightRecognizer_LayerEvents(t *testing.T) {
+	goods := []model.Goods{{ID: "000001", Weight: 550}}
+	stocks := []model.Stock{{GoodsID: "000001", Layer: 1, Num: 10}}
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance: 5,
+		Goods:           goods,
+		Stocks:          stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	samples := samplesAt(1, start, 100*time.Millisecond, 5000, 5800, 5001, 4450, 4452, 4451, 5000, 5002)
+
+	events := recognizer.LayerEvents(samples)
+	if len(events) != 2 || events[0].Delta != model.Grams(-548) || events[1].Delta != model.Grams(550) {
+		t.Errorf("应该提取出取走和放回两个事件，实际为%+v", events)
+	}
+}
+
+// TestWeightRecognizer_DetectSwaps 测试检测取走商品后很快放回等重物品
+func TestWeightRecognizer_DetectSwaps(t *testing.T) {
+	goods := []model.Goods{{ID: "000001", Weight: 550}}
+	stocks := []model.Stock{{GoodsID: "000001", Layer: 1, Num: 10}}
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance: 5,
+		Goods:           goods,
+		Stocks:          stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	samples := samplesAt(1, start, time.Second, 5000, 4450, 4451, 5003, 5003)
+
+	exceptions := recognizer.DetectSwaps(samples, 5*time.Second)
+	if len(exceptions) != 1 || exceptions[0].Exception != exception.SwapSuspectedError {
+		t.Fatalf("应该检测到调包嫌疑，实际结果%v", exceptions)
+	}
+	evidence := exceptions[0].Swap
+	if evidence == nil || evidence.GoodsID != "000001" || evidence.Interval() != 2*time.Second {
+		t.Errorf("调包证据错误：%+v", evidence)
+	}
+
+	// 放回间隔过长
+	if exceptions := recognizer.DetectSwaps(samples, time.Second); len(exceptions) != 0 {
+		t.Errorf("超过时间窗口不应该视为调包，实际结果%v", exceptions)
+	}
+
+	// 放回的重量不同
+	samples = samplesAt(1, start, time.Second, 5000, 4450, 4451, 4800, 4800)
+	if exceptions := recognizer.DetectSwaps(samples, 5*time.Second); len(exceptions) != 0 {
+		t.Errorf("放回重量不同不应该视为调包，实际结果%v", exceptions)
+	}
+
+	// 正常购买
+	samples = samplesAt(1, start, time.Second, 5000, 4450, 4451)
+	if exceptions := recognizer.DetectSwaps(samples, 5*time.Second); len(exceptions) != 0 {
+		t.Errorf("正常购买不应该视为调包，实际结果%v", exceptions)
+	}
+}
+
+// TestWeightRecognizer_DetectSwapsPrecise 测试只有精确读数时检测调包
+func TestWeightRecognizer_DetectSwapsPrecise(t *testing.T) {
+	goods := []model.Goods{{ID: "000001", Weight: 3, Precise: model.GramsFloat(3.2)}}
+	stocks := []model.Stock{{GoodsID: "000001", Layer: 1, Num: 50}}
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		Resolution:    100 * model.Milligram,
+		PreciseSensor: model.GramsFloat(0.2),
+		Goods:         goods,
+		Stocks:        stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	samples := make([]model.Sample, 0)
+	for i, weight := range []float64{100, 96.8, 96.8, 100.1, 100.1} {
+		samples = append(samples, model.Sample{Layer: 1, Precise: model.GramsFloat(weight), Time: start.Add(time.Duration(i) * time.Second)})
+	}
+
+	exceptions := recognizer.DetectSwaps(samples, 5*time.Second)
+	if len(exceptions) != 1 || exceptions[0].Exception != exception.SwapSuspectedError {
+		t.Errorf("应该根据精确读数检测到调包嫌疑，实际结果%v", exceptions)
+	}
+}
@@ -0,0 +1,205 @@
+package recognition
+
+import (
+	"VendingMachineWeightRecognition/pkg/model"
+	"fmt"
+	"math"
+)
+
+// Tolerance 包装容差，由绝对值和百分比组成，两者同时生效
+// 单件商品的允许偏差为 绝对值 + 单件重量 × 百分比
+// 通过 PercentTolerance、FractionTolerance、GramsTolerance、WeightTolerance、CombinedTolerance 创建，零值表示没有容差
+type Tolerance struct {
+	absolute model.Weight // 绝对容差
+	percent  float64      // 相对容差，百分比
+}
+
+// PercentTolerance 创建百分比容差，5 表示单件重量的 5%
+func PercentTolerance(percent float64) Tolerance {
+	return Tolerance{percent: percent}
+}
+
+// FractionTolerance 创建比例容差，0.05 表示单件重量的 5%
+func FractionTolerance(fraction float64) Tolerance {
+	return Tolerance{percent: fraction * 100}
+}
+
+// GramsTolerance 创建绝对容差，单位 g
+func GramsTolerance(grams int) Tolerance {
+	return Tolerance{absolute: model.Grams(grams)}
+}
+
+// WeightTolerance 创建克以下精度的绝对容差
+func WeightTolerance(absolute model.Weight) Tolerance {
+	return Tolerance{absolute: absolute}
+}
+
+// CombinedTolerance 创建绝对值与百分比同时生效的容差
+func CombinedTolerance(grams int, percent float64) Tolerance {
+	return Tolerance{absolute: model.Grams(grams), percent: percent}
+}
+
+// Grams 返回四舍五入到整数克的绝对容差
+func (t Tolerance) Grams() int {
+	return t.absolute.RoundGrams()
+}
+
+// Absolute 返回绝对容差
+func (t Tolerance) Absolute() model.Weight {
+	return t.absolute
+}
+
+// Percent 返回相对容差，百分比
+func (t Tolerance) Percent() float64 {
+	return t.percent
+}
+
+// Validate 检查容差是否合理：绝对值非负，百分比在 [0, 100) 范围内
+func (t Tolerance) Validate() error {
+	if t.absolute < 0 {
+		return fmt.Errorf("绝对容差不能为负数，实际为 %v", t.absolute)
+	}
+	if math.IsNaN(t.percent) || t.percent < 0 || t.percent >= 100 {
+		return fmt.Errorf("相对容差必须在 [0, 100) 百分比范围内，实际为 %v%%", t.percent)
+	}
+	return nil
+}
+
+// String 返回容差的可读形式
+func (t Tolerance) String() string {
+	switch {
+	case t.absolute != 0 && t.percent != 0:
+		return fmt.Sprintf("%v+%v%%", t.absolute, t.percent)
+	case t.absolute != 0:
+		return t.absolute.String()
+	default:
+		return fmt.Sprintf("%v%%", t.percent)
+	}
+}
+
+// slack 返回单件重量为 weight 的商品的允许偏差，weight 和返回值都以 unit 为单位
+func (t Tolerance) slack(weight int, unit model.Weight) float64 {
+	return float64(t.absolute)/float64(unit) + float64(weight)*t.percent/100
+}
+
+// validateSensorTolerance 检查传感器容差是否在量程范围内
+func validateSensorTolerance(sensorTolerance model.Weight) error {
+	if sensorTolerance < 0 || sensorTolerance > model.Grams(maxSensorWeight) {
+		return fmt.Errorf("传感器容差必须在 [0, %dg] 范围内，实际为 %v", maxSensorWeight, sensorTolerance)
+	}
+	return nil
+}
+
+// ToleranceOverrides 分层和分商品的容差覆盖
+// 每件商品的包装容差按 商品覆盖 > 层覆盖 > 全局容差 的顺序确定
+type ToleranceOverrides struct {
+	LayerSensor  map[int]model.Weight // 层号到传感器容差的映射
+	LayerPackage map[int]Tolerance    // 层号到该层商品默认包装容差的映射
+	GoodsPackage map[string]Tolerance // 商品编号到包装容差的映射
+}
+
+// Validate 检查所有覆盖值是否合理
+func (o ToleranceOverrides) Validate() error {
+	for layer, tolerance := range o.LayerSensor {
+		if err := validateSensorTolerance(tolerance); err != nil {
+			return fmt.Errorf("第%d层: %w", layer, err)
+		}
+	}
+	for layer, tolerance := range o.LayerPackage {
+		if err := tolerance.Validate(); err != nil {
+			return fmt.Errorf("第%d层: %w", layer, err)
+		}
+	}
+	for goodsID, tolerance := range o.GoodsPackage {
+		if err := tolerance.Validate(); err != nil {
+			return fmt.Errorf("商品%s: %w", goodsID, err)
+		}
+	}
+	return nil
+}
+
+// clone 深拷贝容差覆盖
+func (o ToleranceOverrides) clone() ToleranceOverrides {
+	copied := ToleranceOverrides{
+		LayerSensor:  make(map[int]model.Weight, len(o.LayerSensor)),
+		LayerPackage: make(map[int]Tolerance, len(o.LayerPackage)),
+		GoodsPackage: make(map[string]Tolerance, len(o.GoodsPackage)),
+	}
+	for layer, tolerance := range o.LayerSensor {
+		copied.LayerSensor[layer] = tolerance
+	}
+	for layer, tolerance := range o.LayerPackage {
+		copied.LayerPackage[layer] = tolerance
+	}
+	for goodsID, tolerance := range o.GoodsPackage {
+		copied.GoodsPackage[goodsID] = tolerance
+	}
+	return copied
+}
+
+// layerTolerance 某层的有效容差
+// 重量都以识别器的精度为单位
+type layerTolerance struct {
+	sensor   int       // 传感器容差
+	slacks   []float64 // 各商品单件的允许偏差，与 layerGoodsMap 中的商品一一对应
+	maxRatio float64   // 单件允许偏差与单件重量之比的最大值
+}
+
+// SetToleranceOverrides 原子地设置分层和分商品的容差覆盖，未覆盖的部分使用全局容差
+// 覆盖值不合理时返回错误，识别器保持原有设置
+func (wr *WeightRecognizer) SetToleranceOverrides(overrides ToleranceOverrides) error {
+	if err := overrides.Validate(); err != nil {
+		return err
+	}
+	copied := overrides.clone()
+
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+
+	current := wr.state.Load()
+	next := *current
+	next.overrides = copied
+	wr.state.Store(&next)
+
+	return nil
+}
+
+// resolveTolerance 解析某层的有效容差
+// 传感器容差按 分层覆盖 > 自动容差 > 全局容差 的顺序确定
+func (wr *WeightRecognizer) resolveTolerance(snap *catalogSnapshot, layer int) layerTolerance {
+	tol := layerTolerance{sensor: wr.ticks(wr.sensorTolerance)}
+	if sensor, ok := snap.autoSensor[layer]; ok {
+		// 自动容差向上取整到识别精度，避免舍入后小于噪声
+		tol.sensor = int(math.Ceil(float64(sensor) / float64(wr.resolution)))
+	}
+	if sensor, ok := snap.overrides.LayerSensor[layer]; ok {
+		tol.sensor = wr.ticks(sensor)
+	}
+
+	layerPackage, hasLayerPackage := snap.overrides.LayerPackage[layer]
+	goods := snap.layerGoodsMap[layer]
+	tol.slacks = make([]float64, len(goods))
+	for i, good := range goods {
+		tol.slacks[i] = wr.goodsTolerance(snap, good, layerPackage, hasLayerPackage).slack(good.Weight, wr.resolution)
+
+		if good.Weight <= 0 {
+			continue
+		}
+		if ratio := tol.slacks[i] / float64(good.Weight); ratio > tol.maxRatio {
+			tol.maxRatio = ratio
+		}
+	}
+
+	return tol
+}
+
+// goodsTolerance 确定单个商品的包装容差
+func (wr *WeightRecognizer) goodsTolerance(snap *catalogSnapshot, good model.Goods, layerPackage Tolerance, hasLayerPackage bool) Tolerance {
+	if tolerance, ok := snap.overrides.GoodsPackage[good.ID]; ok {
+		return tolerance
+	}
+	if hasLayerPackage {
+		return layerPackage
+	}
+	return wr.packageTolerance
+}
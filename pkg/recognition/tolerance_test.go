@@ -0,0 +1,213 @@
+package recognition
+
+import (
+	"VendingMachineWeightRecognition/pkg/exception"
+	"VendingMachineWeightRecognition/pkg/model"
+	"math"
+	"testing"
+)
+
+// TestWeightRecognizer_LayerSensorOverride 测试分层传感器容差
+func TestWeightRecognizer_LayerSensorOverride(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Weight: 100},
+	}
+
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 10},
+		{GoodsID: "000001", Layer: 2, Num: 10},
+	}
+
+	beginLayers := []model.Layer{
+		{Index: 1, Weight: 1000},
+		{Index: 2, Weight: 1000},
+	}
+
+	endLayers := []model.Layer{
+		{Index: 1, Weight: 875},
+		{Index: 2, Weight: 875}, // 第2层使用大量程传感器，误差更大
+	}
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance: 10,
+		Goods:           goods,
+		Stocks:          stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := recognizer.SetToleranceOverrides(ToleranceOverrides{
+		LayerSensor: map[int]model.Weight{2: model.Grams(30)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	result := recognizer.Recognize(beginLayers, endLayers)
+	if len(result.Exceptions) != 1 || result.Exceptions[0].Layer != 1 || result.Exceptions[0].Exception != exception.RecognitionError {
+		t.Errorf("第1层应该检测到识别异常，实际结果%v", result.Exceptions)
+	}
+	if len(result.Items) != 1 || result.Items[0].Num != 1 {
+		t.Errorf("第2层应该识别出1个商品，实际识别出%v", result.Items)
+	}
+}
+
+// TestWeightRecognizer_GoodsPackageOverride 测试分商品包装容差按组合中的每件商品生效
+func TestWeightRecognizer_GoodsPackageOverride(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Weight: 200}, // 散装，包装误差大
+		{ID: "000002", Weight: 500},
+	}
+
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 10},
+		{GoodsID: "000002", Layer: 1, Num: 10},
+	}
+
+	beginLayers := []model.Layer{{Index: 1, Weight: 3000}}
+	endLayers := []model.Layer{{Index: 1, Weight: 2270}} // 1个商品1（偏重25g）和1个商品2
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance: 5,
+		Goods:           goods,
+		Stocks:          stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := recognizer.Recognize(beginLayers, endLayers)
+	if len(result.Exceptions) != 1 {
+		t.Fatalf("没有容差覆盖时应该无法识别，实际结果%v", result.Items)
+	}
+
+	if err := recognizer.SetToleranceOverrides(ToleranceOverrides{
+		GoodsPackage: map[string]Tolerance{"000001": CombinedTolerance(10, 10)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	result = recognizer.Recognize(beginLayers, endLayers)
+	if len(result.Items) != 2 {
+		t.Errorf("商品1的容差为30g，应该识别出2个商品，实际识别出%v", result.Items)
+	}
+
+	// 商品覆盖优先于层覆盖
+	if err := recognizer.SetToleranceOverrides(ToleranceOverrides{
+		LayerPackage: map[int]Tolerance{1: PercentTolerance(0)},
+		GoodsPackage: map[string]Tolerance{"000001": GramsTolerance(30)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	result = recognizer.Recognize(beginLayers, endLayers)
+	if len(result.Items) != 2 {
+		t.Errorf("商品覆盖应该优先于层覆盖，实际识别出%v", result.Items)
+	}
+}
+
+// TestWeightRecognizer_MixedToleranceFallback 测试容差比例不同时索引回退到分支定界
+func TestWeightRecognizer_MixedToleranceFallback(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Weight: 100},
+		{ID: "000002", Weight: 200},
+	}
+
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 10},
+		{GoodsID: "000002", Layer: 1, Num: 10},
+	}
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		Goods:  goods,
+		Stocks: stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := recognizer.SetToleranceOverrides(ToleranceOverrides{
+		GoodsPackage: map[string]Tolerance{"000001": FractionTolerance(0.1)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 200g 的最少件数组合是1个商品2，但它没有容差；2个商品1的允许偏差为20g
+	result := recognizer.Recognize(
+		[]model.Layer{{Index: 1, Weight: 3000}},
+		[]model.Layer{{Index: 1, Weight: 2785}},
+	)
+	if len(result.Items) != 1 || result.Items[0].GoodsID != "000001" || result.Items[0].Num != 2 {
+		t.Errorf("应该识别出2个商品1，实际识别出%v", result.Items)
+	}
+}
+
+// TestTolerance_Units 测试不同单位的容差构造
+func TestTolerance_Units(t *testing.T) {
+	if PercentTolerance(5).slack(200, model.Gram) != 10 {
+		t.Errorf("200g的5%%应该是10g，实际为%f", PercentTolerance(5).slack(200, model.Gram))
+	}
+	if FractionTolerance(0.05).slack(200, model.Gram) != 10 {
+		t.Errorf("200g的0.05倍应该是10g，实际为%f", FractionTolerance(0.05).slack(200, model.Gram))
+	}
+	if GramsTolerance(3).slack(200, model.Gram) != 3 {
+		t.Errorf("绝对容差应该是3g，实际为%f", GramsTolerance(3).slack(200, model.Gram))
+	}
+	if CombinedTolerance(3, 5).slack(200, model.Gram) != 13 {
+		t.Errorf("组合容差应该是13g，实际为%f", CombinedTolerance(3, 5).slack(200, model.Gram))
+	}
+}
+
+// TestTolerance_Validate 测试不合理的容差被拒绝
+func TestTolerance_Validate(t *testing.T) {
+	invalid := []Tolerance{
+		GramsTolerance(-1),
+		PercentTolerance(-5),
+		PercentTolerance(100),
+		FractionTolerance(5), // 误把百分比当作比例
+		PercentTolerance(math.NaN()),
+	}
+	for _, tolerance := range invalid {
+		if tolerance.Validate() == nil {
+			t.Errorf("容差%v应该被拒绝", tolerance)
+		}
+	}
+
+	valid := []Tolerance{
+		{},
+		PercentTolerance(5),
+		FractionTolerance(0.05),
+		CombinedTolerance(10, 2.5),
+	}
+	for _, tolerance := range valid {
+		if err := tolerance.Validate(); err != nil {
+			t.Errorf("容差%v应该合法，实际错误%v", tolerance, err)
+		}
+	}
+}
+
+// TestNewWeightRecognizerWithOptions 测试创建识别器时校验容差
+func TestNewWeightRecognizerWithOptions(t *testing.T) {
+	goods := []model.Goods{{ID: "000001", Weight: 100}}
+	stocks := []model.Stock{{GoodsID: "000001", Layer: 1, Num: 10}}
+
+	if _, err := NewWeightRecognizerWithOptions(Options{SensorTolerance: -1, Goods: goods, Stocks: stocks}); err == nil {
+		t.Error("负的传感器容差应该被拒绝")
+	}
+	if _, err := NewWeightRecognizerWithOptions(Options{SensorTolerance: 10, PackageTolerance: PercentTolerance(150), Goods: goods, Stocks: stocks}); err == nil {
+		t.Error("超过100%的包装容差应该被拒绝")
+	}
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance:  10,
+		PackageTolerance: FractionTolerance(0.05),
+		Goods:            goods,
+		Stocks:           stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := recognizer.Recognize([]model.Layer{{Index: 1, Weight: 1000}}, []model.Layer{{Index: 1, Weight: 885}})
+	if len(result.Items) != 1 || result.Items[0].Num != 1 {
+		t.Errorf("应该识别出1个商品，实际识别出%v", result.Items)
+	}
+
+	if err := recognizer.SetToleranceOverrides(ToleranceOverrides{LayerSensor: map[int]model.Weight{1: model.Grams(-5)}}); err == nil {
+		t.Error("负的分层传感器容差应该被拒绝")
+	}
+}
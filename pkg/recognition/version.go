@@ -0,0 +1,100 @@
+package recognition
+
+import (
+	"VendingMachineWeightRecognition/pkg/model"
+	"sort"
+	"sync"
+	"time"
+)
+
+// catalogVersions 按需计算的各版本序号的目录
+type catalogVersions struct {
+	mu       sync.Mutex
+	versions map[int]catalogVersion
+}
+
+// at 返回 t 时刻生效的商品目录对应的快照，t 为零值时使用当前时间
+// 商品没有重量版本或 t 时刻的版本与快照相同时直接返回 snap，否则返回替换了商品重量数据的浅拷贝
+// 其他版本的数据只依赖商品、库存和先验，首次使用时计算后缓存，容差覆盖等设置始终取自 snap
+func (snap *catalogSnapshot) at(t time.Time, resolution model.Weight) *catalogSnapshot {
+	if len(snap.changes) == 0 {
+		return snap
+	}
+	if t.IsZero() {
+		t = time.Now()
+	}
+	epoch := epochAt(snap.changes, t)
+	if epoch == snap.epoch {
+		return snap
+	}
+
+	snap.versions.mu.Lock()
+	version, ok := snap.versions.versions[epoch]
+	if !ok {
+		version = snap.buildVersion(epoch, &snap.catalogVersion, resolution)
+		snap.versions.versions[epoch] = version
+	}
+	snap.versions.mu.Unlock()
+
+	versioned := *snap
+	versioned.catalogVersion = version
+	versioned.epoch = epoch
+	return &versioned
+}
+
+// epochTime 返回版本序号 epoch 开始生效的时间，序号 0 为零值，即最早版本生效之前
+func (snap *catalogSnapshot) epochTime(epoch int) time.Time {
+	if epoch == 0 {
+		return time.Time{}
+	}
+	return snap.changes[epoch-1]
+}
+
+// epochAt 返回 t 时刻的版本序号，即不晚于 t 的生效时间个数
+func epochAt(changes []time.Time, t time.Time) int {
+	return sort.Search(len(changes), func(i int) bool {
+		return changes[i].After(t)
+	})
+}
+
+// revisionTimes 收集所有商品重量版本的生效时间，从早到晚排序并去重
+func revisionTimes(goods []model.Goods) []time.Time {
+	var changes []time.Time
+	for _, good := range goods {
+		for _, revision := range good.Revisions {
+			changes = append(changes, revision.EffectiveFrom)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Before(changes[j])
+	})
+
+	unique := changes[:0]
+	for i, change := range changes {
+		if i == 0 || !change.Equal(changes[i-1]) {
+			unique = append(unique, change)
+		}
+	}
+	return unique
+}
+
+// goodsAt 返回 t 时刻生效的商品目录
+func goodsAt(goods []model.Goods, t time.Time) []model.Goods {
+	versioned := make([]model.Goods, len(goods))
+	for i, good := range goods {
+		versioned[i] = good.At(t)
+	}
+	return versioned
+}
+
+// copyGoods 复制商品目录，包括组合装和重量版本
+func copyGoods(goods []model.Goods) []model.Goods {
+	copied := make([]model.Goods, len(goods))
+	for i, good := range goods {
+		good.Components = append([]model.BundleComponent(nil), good.Components...)
+		good.Revisions = append([]model.GoodsRevision(nil), good.Revisions...)
+		good.Info = good.Info.Clone()
+		copied[i] = good
+	}
+	return copied
+}
@@ -0,0 +1,98 @@
+package recognition
+
+import (
+	"VendingMachineWeightRecognition/pkg/model"
+	"context"
+	"testing"
+	"time"
+)
+
+// TestWeightRecognizer_VersionedCatalog 测试按会话开始时生效的商品重量版本识别
+func TestWeightRecognizer_VersionedCatalog(t *testing.T) {
+	repack := time.Now().Add(-24 * time.Hour)  // 已经生效的新包装
+	upcoming := time.Now().Add(24 * time.Hour) // 尚未生效的新包装
+
+	goods := []model.Goods{
+		{ID: "000001", Weight: 500, Revisions: []model.GoodsRevision{{EffectiveFrom: repack, Weight: 450}}},
+		{ID: "000002", Weight: 300, Revisions: []model.GoodsRevision{{EffectiveFrom: upcoming, Weight: 280}}},
+		{ID: "000003", Components: []model.BundleComponent{{GoodsID: "000001", Num: 2}}},
+	}
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 10},
+		{GoodsID: "000002", Layer: 2, Num: 10},
+		{GoodsID: "000003", Layer: 3, Num: 3}, // 与散装单品同层时无法区分，见 TestWeightRecognizer_BundleOrLoose
+	}
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance: 5,
+		Goods:           goods,
+		Stocks:          stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		beginTime time.Time
+		layer     int
+		diff      int
+		goodsID   string
+	}{
+		{"换包装前的会话按旧重量识别", repack.Add(-time.Hour), 1, 500, "000001"},
+		{"换包装后的会话按新重量识别", repack.Add(time.Hour), 1, 450, "000001"},
+		{"未填写时间按当前生效的版本识别", time.Time{}, 1, 450, "000001"},
+		{"组合装重量随所含商品的版本变化", repack.Add(-time.Hour), 3, 1000, "000003"},
+		{"组合装使用新版本计算重量", repack.Add(time.Hour), 3, 900, "000003"},
+		{"尚未生效的版本不影响当前识别", time.Time{}, 2, 300, "000002"},
+		{"延迟上报的会话按生效后的版本识别", upcoming.Add(time.Hour), 2, 280, "000002"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := recognizer.RecognizeSession(context.Background(), model.Session{
+				BeginLayers: []model.Layer{{Index: tt.layer, Weight: 10000}},
+				EndLayers:   []model.Layer{{Index: tt.layer, Weight: 10000 - tt.diff}},
+				BeginTime:   tt.beginTime,
+			})
+			if !result.Successful || len(result.Items) != 1 || result.Items[0].GoodsID != tt.goodsID || result.Items[0].Num != 1 {
+				t.Errorf("应该识别出1个商品%s，实际结果%+v", tt.goodsID, result)
+			}
+		})
+	}
+}
+
+// TestWeightRecognizer_UpdateGoodsWeightsVersioned 测试学习到的重量写入当前生效的版本
+func TestWeightRecognizer_UpdateGoodsWeightsVersioned(t *testing.T) {
+	repack := time.Now().Add(-time.Hour)
+	goods := []model.Goods{
+		{ID: "000001", Weight: 500, Revisions: []model.GoodsRevision{{EffectiveFrom: repack, Weight: 450}}},
+	}
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance: 5,
+		Goods:           goods,
+		Stocks:          []model.Stock{{GoodsID: "000001", Layer: 1, Num: 10}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recognizer.UpdateGoodsWeights(map[string]model.Weight{"000001": model.Grams(460)})
+
+	updated := recognizer.Goods()[0]
+	if updated.Weight != 460 || updated.Revisions[0].Weight != 460 {
+		t.Errorf("应该更新当前生效的版本，实际为%+v", updated)
+	}
+	if goods[0].Revisions[0].Weight != 450 {
+		t.Error("不应该修改调用方的商品目录")
+	}
+
+	// 换包装前的会话仍按旧重量识别
+	result := recognizer.RecognizeSession(context.Background(), model.Session{
+		BeginLayers: []model.Layer{{Index: 1, Weight: 5000}},
+		EndLayers:   []model.Layer{{Index: 1, Weight: 4500}},
+		BeginTime:   repack.Add(-time.Hour),
+	})
+	if !result.Successful || len(result.Items) != 1 || result.Items[0].Num != 1 {
+		t.Errorf("应该按旧重量识别出1个商品，实际结果%+v", result)
+	}
+}
@@ -0,0 +1,25 @@
+package recognition
+
+import (
+	"VendingMachineWeightRecognition/pkg/exception"
+	"VendingMachineWeightRecognition/pkg/model"
+	"VendingMachineWeightRecognition/pkg/sensor"
+	"time"
+)
+
+// checkVibration 检查购物前后的快照是否处于整机碰撞窗口内
+func (wr *WeightRecognizer) checkVibration(session model.Session) []RecognitionException {
+	if wr.bump == nil || len(session.Samples) == 0 {
+		return nil
+	}
+
+	windows := sensor.DetectBumps(session.Samples, *wr.bump)
+	exceptions := make([]RecognitionException, 0)
+	for _, at := range []time.Time{session.BeginTime, session.EndTime} {
+		if at.IsZero() || sensor.Reliable(windows, at) {
+			continue
+		}
+		exceptions = append(exceptions, RecognitionException{Exception: exception.VibrationError})
+	}
+	return exceptions
+}
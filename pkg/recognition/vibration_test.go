@@ -0,0 +1,52 @@
+package recognition
+
+import (
+	"VendingMachineWeightRecognition/pkg/exception"
+	"VendingMachineWeightRecognition/pkg/model"
+	"VendingMachineWeightRecognition/pkg/sensor"
+	"context"
+	"testing"
+	"time"
+)
+
+// TestWeightRecognizer_Vibration 测试快照处于碰撞窗口内时不识别
+func TestWeightRecognizer_Vibration(t *testing.T) {
+	goods := []model.Goods{{ID: "000001", Weight: 90}}
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 10},
+		{GoodsID: "000001", Layer: 2, Num: 10},
+	}
+
+	config := sensor.DefaultBumpConfig()
+	recognizer, err := NewWeightRecognizerWithOptions(Options{SensorTolerance: 5, Bump: &config, Goods: goods, Stocks: stocks})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	samples := append(
+		samplesAt(1, start, 100*time.Millisecond, 5000, 5000, 4910, 5001, 5000),
+		samplesAt(2, start, 100*time.Millisecond, 3000, 3000, 3050, 3000, 3000)...,
+	)
+
+	// 购物后的快照恰好在碰撞时取得，第1层看起来少了1件
+	session := model.Session{
+		BeginLayers: []model.Layer{{Index: 1, Weight: 5000}, {Index: 2, Weight: 3000}},
+		EndLayers:   []model.Layer{{Index: 1, Weight: 4910}, {Index: 2, Weight: 3050}},
+		Samples:     samples,
+		BeginTime:   start.Add(-time.Second),
+		EndTime:     start.Add(200 * time.Millisecond),
+	}
+	result := recognizer.RecognizeSession(context.Background(), session)
+	if len(result.Exceptions) != 1 || result.Exceptions[0].Exception != exception.VibrationError || len(result.Items) != 0 {
+		t.Errorf("应该检测到碰撞并且不识别，实际结果%v %v", result.Items, result.Exceptions)
+	}
+
+	// 碰撞结束后的快照正常识别
+	session.EndLayers = []model.Layer{{Index: 1, Weight: 5000}, {Index: 2, Weight: 3000}}
+	session.EndTime = start.Add(2 * time.Second)
+	result = recognizer.RecognizeSession(context.Background(), session)
+	if len(result.Exceptions) != 0 || len(result.Items) != 0 {
+		t.Errorf("碰撞结束后应该没有购物和异常，实际结果%v %v", result.Items, result.Exceptions)
+	}
+}
@@ -0,0 +1,44 @@
+package recognition
+
+import (
+	"VendingMachineWeightRecognition/pkg/model"
+	"context"
+)
+
+// recognizeWeighed 识别含有称重商品的层
+// 重量变化落在某个称重商品的合理份量范围内时，按实际重量计为该商品
+// 层上同时有按件商品时，按件组合与称重商品都能解释重量变化视为无法识别，避免误收费
+func (wr *WeightRecognizer) recognizeWeighed(ctx context.Context, snap *catalogSnapshot, layer int, tol layerTolerance, weightDiff int) ([]RecognitionItem, SearchStats) {
+	candidates := make([]model.Goods, 0)
+	for _, good := range snap.layerWeighed[layer] {
+		if weightDiff < good.MinPortion-tol.sensor {
+			continue
+		}
+		if good.MaxPortion > 0 && weightDiff > good.MaxPortion+tol.sensor {
+			continue
+		}
+		if weightDiff > snap.layerStockMap[layer][good.ID]+tol.sensor {
+			continue // 超过库存重量
+		}
+		candidates = append(candidates, good)
+	}
+
+	unitItems := make([]RecognitionItem, 0)
+	stats := SearchStats{Explored: 1, Total: 1}
+	if len(snap.layerGoodsMap[layer]) > 0 {
+		unitItems, stats = wr.recognizeUnits(ctx, snap, layer, tol, weightDiff)
+	}
+
+	switch {
+	case len(candidates) == 0:
+		return unitItems, stats
+	case len(candidates) == 1 && len(unitItems) == 0:
+		return []RecognitionItem{{
+			GoodsID: candidates[0].ID,
+			Num:     1,
+			Weight:  wr.grams(weightDiff),
+		}}, stats
+	default:
+		return nil, stats
+	}
+}
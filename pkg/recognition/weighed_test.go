@@ -0,0 +1,135 @@
+package recognition
+
+import (
+	"VendingMachineWeightRecognition/pkg/exception"
+	"VendingMachineWeightRecognition/pkg/model"
+	"testing"
+)
+
+// TestWeightRecognizer_WeighedGoods 测试称重商品按实际重量识别
+func TestWeightRecognizer_WeighedGoods(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Kind: model.WeighedGoods, MinPortion: 50, MaxPortion: 1000},
+	}
+
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 2000}, // 库存2000g
+	}
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance: 5,
+		Goods:           goods,
+		Stocks:          stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := recognizer.Recognize(
+		[]model.Layer{{Index: 1, Weight: 3000}},
+		[]model.Layer{{Index: 1, Weight: 2663}},
+	)
+	if !result.Successful || len(result.Items) != 1 || result.Items[0].Weight != 337 || result.Items[0].Num != 1 {
+		t.Errorf("应该识别出337g商品1，实际识别出%v", result.Items)
+	}
+
+	// 少于最小份量
+	result = recognizer.Recognize(
+		[]model.Layer{{Index: 1, Weight: 3000}},
+		[]model.Layer{{Index: 1, Weight: 2970}},
+	)
+	if len(result.Exceptions) != 1 || result.Exceptions[0].Exception != exception.RecognitionError {
+		t.Errorf("少于最小份量应该检测到识别异常，实际结果%v", result.Exceptions)
+	}
+
+	// 超过最大份量
+	result = recognizer.Recognize(
+		[]model.Layer{{Index: 1, Weight: 3000}},
+		[]model.Layer{{Index: 1, Weight: 1500}},
+	)
+	if len(result.Exceptions) != 1 {
+		t.Errorf("超过最大份量应该检测到识别异常，实际识别出%v", result.Items)
+	}
+}
+
+// TestWeightRecognizer_WeighedMixedLayer 测试称重商品与按件商品同层
+func TestWeightRecognizer_WeighedMixedLayer(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Kind: model.WeighedGoods, MinPortion: 100, MaxPortion: 300},
+		{ID: "000002", Weight: 500},
+	}
+
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 2000},
+		{GoodsID: "000002", Layer: 1, Num: 10},
+	}
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance: 5,
+		Goods:           goods,
+		Stocks:          stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 只有按件商品能解释
+	result := recognizer.Recognize([]model.Layer{{Index: 1, Weight: 3000}}, []model.Layer{{Index: 1, Weight: 2000}})
+	if len(result.Items) != 1 || result.Items[0].GoodsID != "000002" || result.Items[0].Num != 2 || result.Items[0].Weight != 0 {
+		t.Errorf("应该识别出2个商品2，实际识别出%v", result.Items)
+	}
+
+	// 只有称重商品能解释
+	result = recognizer.Recognize([]model.Layer{{Index: 1, Weight: 3000}}, []model.Layer{{Index: 1, Weight: 2800}})
+	if len(result.Items) != 1 || result.Items[0].GoodsID != "000001" || result.Items[0].Weight != 200 {
+		t.Errorf("应该识别出200g商品1，实际识别出%v", result.Items)
+	}
+}
+
+// TestWeightRecognizer_WeighedAmbiguous 测试称重商品与按件组合都能解释时不收费
+func TestWeightRecognizer_WeighedAmbiguous(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Kind: model.WeighedGoods, MinPortion: 100, MaxPortion: 1000},
+		{ID: "000002", Weight: 500},
+	}
+
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 2000},
+		{GoodsID: "000002", Layer: 1, Num: 10},
+	}
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance: 5,
+		Goods:           goods,
+		Stocks:          stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := recognizer.Recognize([]model.Layer{{Index: 1, Weight: 3000}}, []model.Layer{{Index: 1, Weight: 2500}})
+	if len(result.Exceptions) != 1 || result.Exceptions[0].Exception != exception.RecognitionError {
+		t.Errorf("500g既可能是称重商品也可能是按件商品，应该检测到识别异常，实际识别出%v", result.Items)
+	}
+}
+
+// TestWeightLearner_SkipsWeighedGoods 测试学习器忽略称重商品
+func TestWeightLearner_SkipsWeighedGoods(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Kind: model.WeighedGoods, MinPortion: 50, MaxPortion: 1000},
+	}
+	stocks := []model.Stock{{GoodsID: "000001", Layer: 1, Num: 2000}}
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance: 5,
+		Goods:           goods,
+		Stocks:          stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	learner := NewWeightLearner(goods, stocks, 5, 1)
+	learner.ObserveResult(recognizer.Recognize([]model.Layer{{Index: 1, Weight: 3000}}, []model.Layer{{Index: 1, Weight: 2663}}))
+
+	if learned, ok := learner.Learned("000001"); ok && learned.Samples > 0 {
+		t.Errorf("称重商品不应该被学习，实际%+v", learned)
+	}
+}
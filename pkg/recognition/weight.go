@@ -3,143 +3,364 @@ package recognition
 import (
 	"VendingMachineWeightRecognition/pkg/exception"
 	"VendingMachineWeightRecognition/pkg/model"
+	"VendingMachineWeightRecognition/pkg/sensor"
+	"context"
+	"fmt"
 	"math"
 	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
-// WeightRecognizer 重量识别器
+// WeightRecognizer 重量识别器，可被多个 goroutine 并发使用
 type WeightRecognizer struct {
-	sensorTolerance  int     // 传感器容差
-	packageTolerance float64 // 包装容差
-	goods            []model.Goods
-	stocks           []model.Stock
-	layerGoodsMap    map[int][]model.Goods  // 层号到商品的映射
-	layerStockMap    map[int]map[string]int // 层号到商品库存的映射
+	resolution       model.Weight // 识别精度，内部重量都以此为单位
+	sensorTolerance  model.Weight // 传感器容差
+	packageTolerance Tolerance    // 包装容差
+	consumption      ConsumptionPolicy
+	cabinetTolerance int           // 整机重量传感器容差，单位 g
+	swapWindow       time.Duration // 调包检测的时间窗口，0 表示不检测
+	bump             *sensor.BumpConfig
+	autoSensor       *AutoSensorTolerance   // 自动传感器容差参数，为空表示使用固定容差
+	noise            *sensor.NoiseEstimator // 各层空闲读数的噪声估计
+
+	mu    sync.Mutex                      // 串行化商品与库存的更新
+	state atomic.Pointer[catalogSnapshot] // 当前生效的商品与库存快照
+}
+
+// Options 识别器的创建参数
+type Options struct {
+	Resolution       model.Weight         // 识别精度，例如 100mg 表示 0.1g，默认 1g
+	SensorTolerance  int                  // 传感器容差，单位 g
+	PreciseSensor    model.Weight         // 克以下精度的传感器容差，非零时优先于 SensorTolerance
+	PackageTolerance Tolerance            // 包装容差
+	Consumption      ConsumptionPolicy    // 部分饮用后放回的计费策略，默认不计费
+	CabinetTolerance int                  // 整机重量传感器容差，单位 g
+	SwapWindow       time.Duration        // 取走商品后在该时间内放回等重物品视为调包嫌疑，0 表示不检测
+	Bump             *sensor.BumpConfig   // 碰撞检测参数，为空表示不检测
+	AutoSensor       *AutoSensorTolerance // 根据空闲读数噪声自动确定传感器容差，为空表示使用固定容差
+	Goods            []model.Goods
+	Stocks           []model.Stock
 }
 
 // NewWeightRecognizer 创建新的重量识别器
+// sensorTolerance 单位为 g，packageTolerance 为百分比，例如 5 表示单件重量的 5%
+//
+// Deprecated: 不校验容差，不合理的参数会得到错误的识别结果，请使用 NewWeightRecognizerWithOptions
 func NewWeightRecognizer(sensorTolerance int, packageTolerance float64, goods []model.Goods, stocks []model.Stock) *WeightRecognizer {
 	wr := &WeightRecognizer{
-		sensorTolerance:  sensorTolerance,
-		packageTolerance: packageTolerance,
-		goods:            goods,
-		stocks:           stocks,
-		layerGoodsMap:    make(map[int][]model.Goods),
-		layerStockMap:    make(map[int]map[string]int),
+		resolution:       model.Gram,
+		sensorTolerance:  model.Grams(sensorTolerance),
+		packageTolerance: PercentTolerance(packageTolerance),
+		noise:            sensor.NewNoiseEstimator(),
 	}
+	wr.state.Store(newCatalogSnapshot(goods, stocks, nil, nil, wr.resolution))
 
-	// 初始化层商品映射
-	for _, stock := range stocks {
-		if _, exists := wr.layerStockMap[stock.Layer]; !exists {
-			wr.layerStockMap[stock.Layer] = make(map[string]int)
-		}
-		wr.layerStockMap[stock.Layer][stock.GoodsID] = stock.Num
+	return wr
+}
 
-		// 找到对应的商品
-		for _, good := range goods {
-			if good.ID == stock.GoodsID {
-				wr.layerGoodsMap[stock.Layer] = append(wr.layerGoodsMap[stock.Layer], good)
-				break
-			}
+// NewWeightRecognizerWithOptions 根据创建参数创建重量识别器，容差不合理时返回错误
+func NewWeightRecognizerWithOptions(options Options) (*WeightRecognizer, error) {
+	if options.Resolution == 0 {
+		options.Resolution = model.Gram
+	}
+	if options.Resolution < 0 {
+		return nil, fmt.Errorf("识别精度必须为正数，实际为 %v", options.Resolution)
+	}
+	sensorTolerance := options.PreciseSensor
+	if sensorTolerance == 0 {
+		sensorTolerance = model.Grams(options.SensorTolerance)
+	}
+	if err := validateSensorTolerance(sensorTolerance); err != nil {
+		return nil, err
+	}
+	if err := options.PackageTolerance.Validate(); err != nil {
+		return nil, fmt.Errorf("包装容差: %w", err)
+	}
+	if options.Consumption < ChargeNone || options.Consumption > ChargeProportional {
+		return nil, fmt.Errorf("未知的部分饮用计费策略 %v", options.Consumption)
+	}
+	if options.CabinetTolerance < 0 {
+		return nil, fmt.Errorf("整机传感器容差不能为负数，实际为 %dg", options.CabinetTolerance)
+	}
+	if options.SwapWindow < 0 {
+		return nil, fmt.Errorf("调包检测时间窗口不能为负数，实际为 %v", options.SwapWindow)
+	}
+	if options.Bump != nil {
+		if err := options.Bump.Validate(); err != nil {
+			return nil, fmt.Errorf("碰撞检测: %w", err)
+		}
+		bump := *options.Bump
+		options.Bump = &bump
+	}
+	if options.AutoSensor != nil {
+		if err := options.AutoSensor.Validate(); err != nil {
+			return nil, fmt.Errorf("自动传感器容差: %w", err)
 		}
+		auto := *options.AutoSensor
+		options.AutoSensor = &auto
 	}
 
-	return wr
+	wr := &WeightRecognizer{
+		resolution:       options.Resolution,
+		sensorTolerance:  sensorTolerance,
+		packageTolerance: options.PackageTolerance,
+		consumption:      options.Consumption,
+		cabinetTolerance: options.CabinetTolerance,
+		swapWindow:       options.SwapWindow,
+		bump:             options.Bump,
+		autoSensor:       options.AutoSensor,
+		noise:            sensor.NewNoiseEstimator(),
+	}
+	wr.state.Store(newCatalogSnapshot(options.Goods, options.Stocks, nil, nil, wr.resolution))
+
+	return wr, nil
 }
 
 // Recognize 识别购物清单
 func (wr *WeightRecognizer) Recognize(beginLayers, endLayers []model.Layer) RecognitionResult {
+	return wr.RecognizeContext(context.Background(), beginLayers, endLayers)
+}
+
+// RecognizeContext 在 ctx 的时限内识别购物清单
+// ctx 取消或超时后停止组合搜索，返回目前找到的最佳结果，并将 Truncated 置为 true
+func (wr *WeightRecognizer) RecognizeContext(ctx context.Context, beginLayers, endLayers []model.Layer) RecognitionResult {
+	return wr.RecognizeSession(ctx, model.Session{BeginLayers: beginLayers, EndLayers: endLayers})
+}
+
+// ticks 将重量换算为识别精度的整数倍
+func (wr *WeightRecognizer) ticks(weight model.Weight) int {
+	return toTicks(weight, wr.resolution)
+}
+
+// weight 将识别精度的整数倍换算为重量
+func (wr *WeightRecognizer) weight(ticks int) model.Weight {
+	return model.Weight(ticks) * wr.resolution
+}
+
+// grams 将识别精度的整数倍换算为四舍五入的整数克
+func (wr *WeightRecognizer) grams(ticks int) int {
+	return wr.weight(ticks).RoundGrams()
+}
+
+// toTicks 将重量按精度 resolution 四舍五入为整数倍
+func toTicks(weight model.Weight, resolution model.Weight) int {
+	return int(math.Round(float64(weight) / float64(resolution)))
+}
+
+// layerReading 一层在购物前后的有效读数
+type layerReading struct {
+	begin      model.Layer
+	end        model.Layer
+	weightDiff int  // 该层减少的重量，以识别精度为单位，负数表示增加
+	inferred   bool // 重量差由整机重量推算，而不是该层传感器的读数
+}
+
+// RecognizeSession 在 ctx 的时限内识别一次购物会话
+// 会话包含整机重量时与各层重量变化之和交叉校验，包含中间读数时检测调包嫌疑
+// 启用碰撞检测且快照处于碰撞窗口内时不进行识别，只上报 VibrationError
+// 商品有重量版本时按 BeginTime 时刻生效的版本识别，BeginTime 为零值时使用当前时间，补传的历史会话按当时的包装识别
+func (wr *WeightRecognizer) RecognizeSession(ctx context.Context, session model.Session) RecognitionResult {
 	result := RecognitionResult{
-		Successful: true,
-		Items:      make([]RecognitionItem, 0),
-		Exceptions: make([]RecognitionException, 0),
+		Successful:       true,
+		Items:            make([]RecognitionItem, 0),
+		Exceptions:       make([]RecognitionException, 0),
+		Layers:           make([]LayerResult, 0),
+		SensorTolerances: make(map[int]model.Weight),
+	}
+
+	if exceptions := wr.checkVibration(session); len(exceptions) > 0 {
+		result.Exceptions = append(result.Exceptions, exceptions...)
+		return result
 	}
 
-	// 按层号排序
+	// 整个识别过程使用同一份快照，避免与并发更新交错；商品重量取会话开始时生效的版本
+	snap := wr.state.Load().at(session.BeginTime, wr.resolution)
+
+	// 复制后按层号排序，不修改调用方的切片
+	beginLayers := append([]model.Layer(nil), session.BeginLayers...)
+	endLayers := append([]model.Layer(nil), session.EndLayers...)
 	sort.Slice(beginLayers, func(i, j int) bool {
 		return beginLayers[i].Index < beginLayers[j].Index
 	})
 	sort.Slice(endLayers, func(i, j int) bool {
 		return endLayers[i].Index < endLayers[j].Index
 	})
+	for i := range beginLayers {
+		beginLayers[i] = normalizeLayer(beginLayers[i])
+	}
+	for i := range endLayers {
+		endLayers[i] = normalizeLayer(endLayers[i])
+	}
 
-	// 处理每一层
+	// 检查传感器异常，收集有效读数
+	readings := make([]layerReading, 0, len(beginLayers))
+	faulty := make([]layerReading, 0)
 	for i := 0; i < len(beginLayers); i++ {
 		beginLayer := beginLayers[i]
 		endLayer := endLayers[i]
 
-		// 检查传感器异常
-		if beginLayer.Weight < 0 || beginLayer.Weight > 32767 ||
-			endLayer.Weight < 0 || endLayer.Weight > 32767 {
+		if !inRange(beginLayer) || !inRange(endLayer) {
 			result.Exceptions = append(result.Exceptions, RecognitionException{
 				Layer:       beginLayer.Index,
 				Exception:   exception.SensorError,
 				BeginWeight: beginLayer.Weight,
 				EndWeight:   endLayer.Weight,
 			})
+			faulty = append(faulty, layerReading{begin: beginLayer, end: endLayer})
 			continue
 		}
 
-		// 检查异物异常
-		if endLayer.Weight > beginLayer.Weight {
-			result.Exceptions = append(result.Exceptions, RecognitionException{
-				Layer:       beginLayer.Index,
-				Exception:   exception.ForeignObjectError,
-				BeginWeight: beginLayer.Weight,
-				EndWeight:   endLayer.Weight,
+		readings = append(readings, layerReading{
+			begin:      beginLayer,
+			end:        endLayer,
+			weightDiff: wr.ticks(beginLayer.Reading() - endLayer.Reading()),
+		})
+	}
+
+	if session.Cabinet != nil {
+		switch len(faulty) {
+		case 0:
+			// 与整机重量交叉校验，定位读数不一致的层
+			result.Exceptions = append(result.Exceptions, wr.crossCheck(ctx, snap, *session.Cabinet, readings)...)
+		case 1:
+			// 降级模式：只有一层传感器故障时，用整机重量减去其余各层的变化推算该层的变化
+			readings = append(readings, wr.inferFaulty(*session.Cabinet, readings, faulty[0]))
+			sort.SliceStable(readings, func(i, j int) bool {
+				return readings[i].begin.Index < readings[j].begin.Index
 			})
-			continue
 		}
+	}
 
-		// 计算重量差
-		weightDiff := beginLayer.Weight - endLayer.Weight
-
-		// 考虑传感器容差，判断是否无购物
-		if weightDiff <= wr.sensorTolerance && weightDiff >= -wr.sensorTolerance {
-			continue // 无购物
+	// 处理每一层
+	for _, reading := range readings {
+		wr.recognizeReading(ctx, snap, reading, &result)
+		if reading.inferred {
+			result.Degraded = true
 		}
+	}
+
+	// 附带商品描述信息
+	snap.describe(result.Items)
+	for _, layer := range result.Layers {
+		snap.describe(layer.Items)
+	}
+
+	// 根据中间读数检测调包嫌疑
+	if len(session.Samples) > 0 && wr.swapWindow > 0 {
+		result.Exceptions = append(result.Exceptions, wr.detectSwaps(snap, session.Samples, wr.swapWindow)...)
+	}
 
-		// 识别该层的商品
-		items := wr.recognizeLayer(beginLayer.Index, weightDiff)
-		if len(items) == 0 {
+	return result
+}
+
+// recognizeReading 识别一层的有效读数，并将结果写入 result
+func (wr *WeightRecognizer) recognizeReading(ctx context.Context, snap *catalogSnapshot, reading layerReading, result *RecognitionResult) {
+	beginLayer := reading.begin
+	endLayer := reading.end
+	weightDiff := reading.weightDiff
+	tol := wr.resolveTolerance(snap, beginLayer.Index)
+	result.SensorTolerances[beginLayer.Index] = wr.weight(tol.sensor)
+
+	// 检查异物异常，推算的重量差允许传感器容差内的负值
+	if weightDiff < 0 && (!reading.inferred || weightDiff < -tol.sensor) {
+		result.Exceptions = append(result.Exceptions, RecognitionException{
+			Layer:       beginLayer.Index,
+			Exception:   exception.ForeignObjectError,
+			BeginWeight: beginLayer.Weight,
+			EndWeight:   endLayer.Weight,
+		})
+		return
+	}
+
+	// 考虑传感器容差，判断是否无购物
+	if weightDiff <= tol.sensor && weightDiff >= -tol.sensor {
+		return // 无购物
+	}
+
+	// 识别该层的商品
+	// 多传感器层先在变化位置附近的货道中识别，无法识别时再在整层识别
+	items, stats := wr.recognizeLanes(ctx, snap, beginLayer, endLayer, tol, weightDiff)
+	if len(items) == 0 {
+		items, stats = wr.recognizeLayer(ctx, snap, beginLayer.Index, tol, weightDiff)
+	}
+
+	// 无法识别时检查是否有商品被部分饮用后放回，搜索提前结束时可能存在未找到的组合，不做推断
+	partial := false
+	if len(items) == 0 && !stats.Truncated {
+		if good, consumed, ok := wr.detectPartial(snap, beginLayer.Index, tol, weightDiff); ok {
 			result.Exceptions = append(result.Exceptions, RecognitionException{
 				Layer:       beginLayer.Index,
-				Exception:   exception.RecognitionError,
+				Exception:   exception.PartialConsumptionError,
 				BeginWeight: beginLayer.Weight,
 				EndWeight:   endLayer.Weight,
+				GoodsID:     good.ID,
+				Consumed:    wr.grams(consumed),
 			})
-			continue
+			items = wr.consumptionItems(good, wr.grams(consumed))
+			partial = true
 		}
+	}
 
-		// 合并相同商品
-		result.Items = wr.mergeItems(result.Items, items)
+	result.Layers = append(result.Layers, LayerResult{
+		Layer:      beginLayer.Index,
+		WeightDiff: wr.grams(weightDiff),
+		Diff:       wr.weight(weightDiff),
+		Items:      items,
+		Search:     stats,
+		Inferred:   reading.inferred,
+	})
+	if stats.Truncated {
+		result.Truncated = true
+	}
+	if len(items) == 0 && !partial {
+		result.Exceptions = append(result.Exceptions, RecognitionException{
+			Layer:       beginLayer.Index,
+			Exception:   exception.RecognitionError,
+			BeginWeight: beginLayer.Weight,
+			EndWeight:   endLayer.Weight,
+		})
+		return
 	}
 
-	return result
+	// 合并相同商品
+	result.Items = wr.mergeItems(result.Items, items)
+}
+
+// recognizeLayer 识别单层的商品，同时返回组合搜索统计
+func (wr *WeightRecognizer) recognizeLayer(ctx context.Context, snap *catalogSnapshot, layer int, tol layerTolerance, weightDiff int) ([]RecognitionItem, SearchStats) {
+	if len(snap.layerWeighed[layer]) > 0 {
+		return wr.recognizeWeighed(ctx, snap, layer, tol, weightDiff)
+	}
+	return wr.recognizeUnits(ctx, snap, layer, tol, weightDiff)
 }
 
-// recognizeLayer 识别单层的商品
-func (wr *WeightRecognizer) recognizeLayer(layer int, weightDiff int) []RecognitionItem {
+// recognizeUnits 识别单层的按件商品
+func (wr *WeightRecognizer) recognizeUnits(ctx context.Context, snap *catalogSnapshot, layer int, tol layerTolerance, weightDiff int) ([]RecognitionItem, SearchStats) {
 	items := make([]RecognitionItem, 0)
-	layerGoods := wr.layerGoodsMap[layer]
+	layerGoods := snap.layerGoodsMap[layer]
 
 	if len(layerGoods) == 0 {
-		return items
+		return items, SearchStats{}
 	}
 
 	// 如果只有一种商品，直接计算数量
 	if len(layerGoods) == 1 {
 		good := layerGoods[0]
-		stock := wr.layerStockMap[layer][good.ID]
+		stock := snap.layerStockMap[layer][good.ID]
 
 		// 考虑包装容差
-		minWeight := int(float64(good.Weight) * (1 - wr.packageTolerance/100))
-		maxWeight := int(float64(good.Weight) * (1 + wr.packageTolerance/100))
+		minWeight := int(float64(good.Weight) - tol.slacks[0])
+		maxWeight := int(float64(good.Weight) + tol.slacks[0])
+		if minWeight <= 0 || maxWeight <= 0 {
+			return items, SearchStats{Explored: 1, Total: 1}
+		}
 
 		// 计算可能的数量范围
 		// 使用浮点数计算以提高精度
-		minNumFloat := float64(weightDiff-wr.sensorTolerance) / float64(maxWeight)
-		maxNumFloat := float64(weightDiff+wr.sensorTolerance) / float64(minWeight)
+		minNumFloat := float64(weightDiff-tol.sensor) / float64(maxWeight)
+		maxNumFloat := float64(weightDiff+tol.sensor) / float64(minWeight)
 
 		// 向上/向下取整
 		minNum := int(math.Ceil(minNumFloat))
@@ -162,117 +383,43 @@ func (wr *WeightRecognizer) recognizeLayer(layer int, weightDiff int) []Recognit
 			})
 		}
 
-		return items
+		// 单商品直接计算，视为完整搜索
+		return items, SearchStats{Explored: 1, Total: 1}
 	}
 
-	// 处理多商品的情况
-	// 按重量从小到大排序，便于组合
-	sort.Slice(layerGoods, func(i, j int) bool {
-		return layerGoods[i].Weight < layerGoods[j].Weight
-	})
-
-	// 检查是否有相同重量的商品
+	// 处理多商品的情况，快照中的层商品已按重量从小到大排序
+	// 检查是否有相同重量且先验相同的商品，先验不同时由后验概率区分
+	costs := snap.layerCosts(layer)
 	hasSameWeight := false
 	for i := 1; i < len(layerGoods); i++ {
-		if layerGoods[i].Weight == layerGoods[i-1].Weight {
+		if layerGoods[i].Weight == layerGoods[i-1].Weight && math.Abs(costs[i]-costs[i-1]) <= scoreEpsilon {
 			hasSameWeight = true
 			break
 		}
 	}
 
-	// 如果有相同重量的商品，直接返回识别异常
+	// 如果有无法区分的相同重量商品，直接返回识别异常
 	if hasSameWeight {
-		return nil
-	}
-
-	// 尝试所有可能的组合
-	bestItems := wr.findBestCombination(layerGoods, layer, weightDiff)
-	if len(bestItems) > 0 {
-		items = append(items, bestItems...)
-	}
-
-	return items
-}
-
-// findBestCombination 查找最佳组合
-func (wr *WeightRecognizer) findBestCombination(goods []model.Goods, layer int, targetWeight int) []RecognitionItem {
-	// 按重量从小到大排序
-	sort.Slice(goods, func(i, j int) bool {
-		return goods[i].Weight < goods[j].Weight
-	})
-
-	// 检查是否有相同重量的商品
-	for i := 1; i < len(goods); i++ {
-		if goods[i].Weight == goods[i-1].Weight {
-			return nil // 有相同重量的商品，返回识别异常
-		}
+		return nil, SearchStats{}
 	}
 
-	// 尝试所有可能的组合
-	bestItems := make([]RecognitionItem, 0)
-	bestDiff := targetWeight + wr.sensorTolerance + 1
-
-	// 生成所有可能的组合
-	maxCombinations := 1 << len(goods) // 2^n
-	for i := 1; i < maxCombinations; i++ {
-		items := make([]RecognitionItem, 0)
-		totalWeight := 0
-		valid := true
-
-		// 检查每个商品是否在当前组合中
-		for j := 0; j < len(goods); j++ {
-			if (i & (1 << j)) != 0 {
-				good := goods[j]
-				stock := wr.layerStockMap[layer][good.ID]
-
-				// 检查库存
-				if stock <= 0 {
-					valid = false
-					break
-				}
-
-				// 考虑包装容差
-				minWeight := int(float64(good.Weight) * (1 - wr.packageTolerance/100))
-				maxWeight := int(float64(good.Weight) * (1 + wr.packageTolerance/100))
-
-				// 使用平均重量
-				avgWeight := (minWeight + maxWeight) / 2
-				totalWeight += avgWeight
-
-				items = append(items, RecognitionItem{
-					GoodsID: good.ID,
-					Num:     1,
-				})
+	// 优先使用预先计算的索引，索引不可用时回退到分支定界搜索
+	if idx := snap.layerIndexMap[layer]; idx != nil {
+		if indexed, scanned, ok := idx.lookup(weightDiff, tol); ok {
+			if bundleAmbiguous(snap, layerGoods, layer, newWeightWindow(weightDiff, tol), indexed) {
+				indexed = nil
 			}
-		}
-
-		if !valid {
-			continue
-		}
-
-		// 计算差异
-		diff := abs(totalWeight - targetWeight)
-
-		// 如果在容差范围内
-		if diff <= wr.sensorTolerance {
-			// 如果找到更好的组合（商品数量更多或差异更小）
-			if len(items) > len(bestItems) || (len(items) == len(bestItems) && diff < bestDiff) {
-				bestItems = items
-				bestDiff = diff
-			}
-		} else if len(bestItems) == 0 && diff < bestDiff {
-			// 如果还没有找到在容差范围内的组合，记录最接近的
-			bestItems = items
-			bestDiff = diff
+			return indexed, SearchStats{Explored: scanned, Total: scanned}
 		}
 	}
 
-	// 如果最小差异超过容差范围的两倍，返回空
-	if bestDiff > wr.sensorTolerance*2 {
-		return nil
+	// 在库存范围内搜索各商品的数量
+	bestItems, stats := wr.findBestCombination(ctx, snap, layerGoods, layer, tol, weightDiff)
+	if len(bestItems) > 0 {
+		items = append(items, bestItems...)
 	}
 
-	return bestItems
+	return items, stats
 }
 
 // min 返回两个整数中的较小值
@@ -291,26 +438,26 @@ func abs(x int) int {
 	return x
 }
 
-// mergeItems 合并相同商品的项
+// mergeItems 合并相同商品的项，称重商品的重量和部分饮用量一并累加
 func (wr *WeightRecognizer) mergeItems(items1, items2 []RecognitionItem) []RecognitionItem {
 	result := make([]RecognitionItem, 0)
-	itemMap := make(map[string]int)
+	itemMap := make(map[string]RecognitionItem)
 
 	// 合并所有项
-	for _, item := range items1 {
-		itemMap[item.GoodsID] += item.Num
-	}
-	for _, item := range items2 {
-		itemMap[item.GoodsID] += item.Num
+	for _, item := range append(append([]RecognitionItem(nil), items1...), items2...) {
+		merged := itemMap[item.GoodsID]
+		merged.GoodsID = item.GoodsID
+		merged.Num += item.Num
+		merged.Weight += item.Weight
+		merged.Consumed += item.Consumed
+		merged.Returned += item.Returned
+		itemMap[item.GoodsID] = merged
 	}
 
 	// 转换回切片
-	for goodsID, num := range itemMap {
-		if num > 0 {
-			result = append(result, RecognitionItem{
-				GoodsID: goodsID,
-				Num:     num,
-			})
+	for _, item := range itemMap {
+		if item.Num > 0 {
+			result = append(result, item)
 		}
 	}
 
@@ -3,7 +3,10 @@ package recognition
 import (
 	"VendingMachineWeightRecognition/pkg/exception"
 	"VendingMachineWeightRecognition/pkg/model"
+	"context"
+	"fmt"
 	"testing"
+	"time"
 )
 
 func TestWeightRecognizer_Recognize(t *testing.T) {
@@ -549,3 +552,97 @@ func TestWeightRecognizer_DuplicateItems(t *testing.T) {
 		t.Errorf("应该合并数量为2，实际为%d", result.Items[0].Num)
 	}
 }
+
+// deepLayerRecognizer 创建一个宽且库存深的单层识别器，规模超过索引上限
+// 商品重量均为偶数且容差为 0，奇数的重量差无解，只能依靠穷尽搜索证明
+func deepLayerRecognizer(t *testing.T) *WeightRecognizer {
+	goods := make([]model.Goods, 0)
+	stocks := make([]model.Stock, 0)
+	for i := 0; i < 24; i++ {
+		id := fmt.Sprintf("%06d", i+1)
+		goods = append(goods, model.Goods{ID: id, Weight: 20 + i*2})
+		stocks = append(stocks, model.Stock{GoodsID: id, Layer: 1, Num: 100})
+	}
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{Goods: goods, Stocks: stocks})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return recognizer
+}
+
+// TestWeightRecognizer_RecognizeContextCanceled 测试取消后的识别
+func TestWeightRecognizer_RecognizeContextCanceled(t *testing.T) {
+	recognizer := deepLayerRecognizer(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result := recognizer.RecognizeContext(ctx,
+		[]model.Layer{{Index: 1, Weight: 30000}},
+		[]model.Layer{{Index: 1, Weight: 26999}},
+	)
+
+	if !result.Truncated {
+		t.Error("取消后的识别应该标记为搜索不完整")
+	}
+	if result.Coverage() >= 1 {
+		t.Errorf("取消后的搜索覆盖率应该小于1，实际为%f", result.Coverage())
+	}
+}
+
+// TestWeightRecognizer_RecognizeContextComplete 测试完整搜索的统计
+func TestWeightRecognizer_RecognizeContextComplete(t *testing.T) {
+	goods := []model.Goods{
+		{ID: "000001", Weight: 100},
+		{ID: "000002", Weight: 500},
+	}
+
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 10},
+		{GoodsID: "000002", Layer: 1, Num: 5},
+	}
+
+	recognizer, err := NewWeightRecognizerWithOptions(Options{
+		SensorTolerance:  10,
+		PackageTolerance: PercentTolerance(5),
+		Goods:            goods,
+		Stocks:           stocks,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := recognizer.RecognizeContext(context.Background(),
+		[]model.Layer{{Index: 1, Weight: 2000}},
+		[]model.Layer{{Index: 1, Weight: 1400}},
+	)
+
+	if result.Truncated {
+		t.Error("未取消的识别不应该标记为搜索不完整")
+	}
+	if result.Coverage() != 1 {
+		t.Errorf("完整搜索的覆盖率应该为1，实际为%f", result.Coverage())
+	}
+	if len(result.Layers) != 1 || result.Layers[0].WeightDiff != 600 {
+		t.Errorf("应该返回第1层的识别详情，实际为%v", result.Layers)
+	}
+}
+
+// TestWeightRecognizer_RecognizeContextDeadline 测试超时后返回已找到的结果
+func TestWeightRecognizer_RecognizeContextDeadline(t *testing.T) {
+	recognizer := deepLayerRecognizer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	result := recognizer.RecognizeContext(ctx,
+		[]model.Layer{{Index: 1, Weight: 30000}},
+		[]model.Layer{{Index: 1, Weight: 26999}},
+	)
+
+	if time.Since(start) > time.Second {
+		t.Errorf("识别应该在时限附近结束，实际耗时%v", time.Since(start))
+	}
+	if !result.Truncated {
+		t.Error("超时的识别应该标记为搜索不完整")
+	}
+}
@@ -0,0 +1,222 @@
+package sensor
+
+import (
+	"VendingMachineWeightRecognition/pkg/model"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// BumpConfig 碰撞检测参数
+type BumpConfig struct {
+	Threshold   model.Weight  // 读数偏离稳定值超过该值视为扰动
+	MaxDuration time.Duration // 扰动在该时间内回到稳定值视为瞬时扰动，否则视为重量真实变化
+	MinLayers   int           // 同时出现瞬时扰动的层数达到该值视为整机受到碰撞
+	Guard       time.Duration // 碰撞窗口前后额外标记为不可靠的时间
+}
+
+// DefaultBumpConfig 返回默认的碰撞检测参数
+func DefaultBumpConfig() BumpConfig {
+	return BumpConfig{
+		Threshold:   model.Grams(20),
+		MaxDuration: 500 * time.Millisecond,
+		MinLayers:   2,
+		Guard:       200 * time.Millisecond,
+	}
+}
+
+// Validate 检查碰撞检测参数是否合理
+func (c BumpConfig) Validate() error {
+	if c.Threshold <= 0 {
+		return fmt.Errorf("扰动阈值必须为正数，实际为 %v", c.Threshold)
+	}
+	if c.MaxDuration <= 0 {
+		return fmt.Errorf("瞬时扰动时长必须为正数，实际为 %v", c.MaxDuration)
+	}
+	if c.MinLayers < 1 {
+		return fmt.Errorf("碰撞层数至少为 1，实际为 %d", c.MinLayers)
+	}
+	if c.Guard < 0 {
+		return fmt.Errorf("保护时间不能为负数，实际为 %v", c.Guard)
+	}
+	return nil
+}
+
+// Window 读数不可靠的时间窗口
+type Window struct {
+	Start  time.Time
+	End    time.Time
+	Layers []int // 出现瞬时扰动的层，按层号排序
+}
+
+// Contains 判断时间 t 是否在窗口内
+func (w Window) Contains(t time.Time) bool {
+	return !t.Before(w.Start) && !t.After(w.End)
+}
+
+// Reliable 判断时间 t 的读数是否不在任何碰撞窗口内
+func Reliable(windows []Window, t time.Time) bool {
+	for _, window := range windows {
+		if window.Contains(t) {
+			return false
+		}
+	}
+	return true
+}
+
+// disturbance 单层的一次瞬时扰动
+type disturbance struct {
+	layer int
+	start time.Time
+	end   time.Time
+}
+
+// DetectBumps 从中间读数中检测整机碰撞
+// 单层读数偏离稳定值后在 MaxDuration 内回到稳定值视为瞬时扰动；偏离持续更久视为取放商品，更新稳定值
+// 至少 MinLayers 层的瞬时扰动在时间上重叠时，标记为碰撞窗口
+func DetectBumps(samples []model.Sample, config BumpConfig) []Window {
+	windows, _ := analyse(samples, config)
+	return windows
+}
+
+// analyse 检测碰撞窗口，同时返回读数末尾仍处于扰动中的层数
+func analyse(samples []model.Sample, config BumpConfig) ([]Window, int) {
+	layers := make(map[int][]model.Sample)
+	for _, sample := range samples {
+		layers[sample.Layer] = append(layers[sample.Layer], sample)
+	}
+
+	disturbances := make([]disturbance, 0)
+	pending := 0
+	for layer, layerSamples := range layers {
+		sort.SliceStable(layerSamples, func(i, j int) bool {
+			return layerSamples[i].Time.Before(layerSamples[j].Time)
+		})
+
+		stable := layerSamples[0].Reading()
+		var start time.Time
+		disturbed := false
+		for _, sample := range layerSamples[1:] {
+			deviation := sample.Reading() - stable
+			if deviation < 0 {
+				deviation = -deviation
+			}
+
+			switch {
+			case !disturbed && deviation > config.Threshold:
+				disturbed = true
+				start = sample.Time
+			case disturbed && deviation <= config.Threshold:
+				disturbed = false
+				disturbances = append(disturbances, disturbance{layer: layer, start: start, end: sample.Time})
+			case disturbed && sample.Time.Sub(start) > config.MaxDuration:
+				// 偏离持续过久，是重量的真实变化
+				disturbed = false
+				stable = sample.Reading()
+			}
+		}
+		if disturbed {
+			pending++
+		}
+	}
+
+	return mergeDisturbances(disturbances, config), pending
+}
+
+// mergeDisturbances 合并时间上重叠的瞬时扰动，涉及的层数足够时形成碰撞窗口
+func mergeDisturbances(disturbances []disturbance, config BumpConfig) []Window {
+	sort.Slice(disturbances, func(i, j int) bool {
+		return disturbances[i].start.Before(disturbances[j].start)
+	})
+
+	windows := make([]Window, 0)
+	for i := 0; i < len(disturbances); {
+		end := disturbances[i].end
+		layers := map[int]bool{disturbances[i].layer: true}
+		j := i + 1
+		for ; j < len(disturbances) && !disturbances[j].start.After(end); j++ {
+			layers[disturbances[j].layer] = true
+			if disturbances[j].end.After(end) {
+				end = disturbances[j].end
+			}
+		}
+
+		if len(layers) >= config.MinLayers {
+			window := Window{
+				Start: disturbances[i].start.Add(-config.Guard),
+				End:   end.Add(config.Guard),
+			}
+			for layer := range layers {
+				window.Layers = append(window.Layers, layer)
+			}
+			sort.Ints(window.Layers)
+			windows = append(windows, window)
+		}
+		i = j
+	}
+
+	return windows
+}
+
+// BumpDetector 实时碰撞检测，可被多个 goroutine 并发使用
+// 控制器在拍摄购物前后的快照前调用 Quiet，避免在碰撞期间取得快照
+type BumpDetector struct {
+	config BumpConfig
+
+	mu      sync.Mutex
+	samples []model.Sample
+}
+
+// NewBumpDetector 创建实时碰撞检测，参数不合理时返回错误
+func NewBumpDetector(config BumpConfig) (*BumpDetector, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &BumpDetector{config: config}, nil
+}
+
+// Observe 记录新的读数，只保留判断所需的最近一段时间
+func (d *BumpDetector) Observe(samples ...model.Sample) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.samples = append(d.samples, samples...)
+	if len(d.samples) == 0 {
+		return
+	}
+
+	latest := d.samples[0].Time
+	for _, sample := range d.samples {
+		if sample.Time.After(latest) {
+			latest = sample.Time
+		}
+	}
+	horizon := latest.Add(-2 * (d.config.MaxDuration + d.config.Guard))
+
+	// 每层保留窗口外的最后一个读数作为稳定值
+	kept := make([]model.Sample, 0, len(d.samples))
+	lastBefore := make(map[int]model.Sample)
+	for _, sample := range d.samples {
+		if sample.Time.Before(horizon) {
+			if previous, ok := lastBefore[sample.Layer]; !ok || sample.Time.After(previous.Time) {
+				lastBefore[sample.Layer] = sample
+			}
+			continue
+		}
+		kept = append(kept, sample)
+	}
+	for _, sample := range lastBefore {
+		kept = append(kept, sample)
+	}
+	d.samples = kept
+}
+
+// Quiet 判断 now 时刻是否可以取得可靠的快照：不在碰撞窗口内，且没有足够多的层正处于扰动中
+func (d *BumpDetector) Quiet(now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	windows, pending := analyse(d.samples, d.config)
+	return Reliable(windows, now) && pending < d.config.MinLayers
+}
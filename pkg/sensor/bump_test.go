@@ -0,0 +1,70 @@
+package sensor
+
+import (
+	"VendingMachineWeightRecognition/pkg/model"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// series 按 100ms 间隔生成某层的读数
+func series(layer int, weights ...int) []model.Sample {
+	samples := make([]model.Sample, len(weights))
+	for i, weight := range weights {
+		samples[i] = model.Sample{Layer: layer, Weight: weight, Time: start.Add(time.Duration(i) * 100 * time.Millisecond)}
+	}
+	return samples
+}
+
+// TestDetectBumps 测试多层同时出现瞬时扰动时标记碰撞窗口
+func TestDetectBumps(t *testing.T) {
+	samples := append(series(1, 5000, 5000, 5090, 4950, 5001, 5000), series(2, 3000, 3000, 3000, 3060, 2999, 3000)...)
+
+	windows := DetectBumps(samples, DefaultBumpConfig())
+	if len(windows) != 1 || len(windows[0].Layers) != 2 {
+		t.Fatalf("应该检测到1个涉及2层的碰撞窗口，实际为%+v", windows)
+	}
+	if Reliable(windows, start.Add(300*time.Millisecond)) {
+		t.Error("碰撞期间的读数应该不可靠")
+	}
+	if !Reliable(windows, start.Add(time.Second)) {
+		t.Error("碰撞结束后的读数应该可靠")
+	}
+}
+
+// TestDetectBumps_IgnoresPurchases 测试单层扰动和重量真实变化不视为碰撞
+func TestDetectBumps_IgnoresPurchases(t *testing.T) {
+	// 第1层取走商品，第2层只有单层的瞬时扰动
+	samples := append(series(1, 5000, 5000, 4500, 4500, 4500, 4500, 4500, 4500, 4501, 4500), series(2, 3000, 3080, 3000, 3000)...)
+	if windows := DetectBumps(samples, DefaultBumpConfig()); len(windows) != 0 {
+		t.Errorf("不应该检测到碰撞，实际为%+v", windows)
+	}
+}
+
+// TestBumpDetector_Quiet 测试实时判断是否可以取得快照
+func TestBumpDetector_Quiet(t *testing.T) {
+	detector, err := NewBumpDetector(DefaultBumpConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	detector.Observe(series(1, 5000, 5000, 5090)...)
+	detector.Observe(series(2, 3000, 3000, 3070)...)
+	if detector.Quiet(start.Add(200 * time.Millisecond)) {
+		t.Error("多层正处于扰动中时不应该取得快照")
+	}
+
+	detector.Observe(model.Sample{Layer: 1, Weight: 5000, Time: start.Add(300 * time.Millisecond)})
+	detector.Observe(model.Sample{Layer: 2, Weight: 3000, Time: start.Add(300 * time.Millisecond)})
+	if detector.Quiet(start.Add(400 * time.Millisecond)) {
+		t.Error("碰撞窗口的保护时间内不应该取得快照")
+	}
+	if !detector.Quiet(start.Add(time.Second)) {
+		t.Error("碰撞结束后应该可以取得快照")
+	}
+
+	if _, err := NewBumpDetector(BumpConfig{}); err == nil {
+		t.Error("零值参数应该被拒绝")
+	}
+}
@@ -0,0 +1,318 @@
+package sensor
+
+import (
+	"VendingMachineWeightRecognition/pkg/model"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+)
+
+// FilterKind 滤波器类型
+type FilterKind string
+
+const (
+	MovingAverageFilter FilterKind = "moving_average" // 滑动平均
+	MedianFilter        FilterKind = "median"         // 滑动中值
+	OutlierFilter       FilterKind = "outlier"        // 野值剔除
+	KalmanFilter        FilterKind = "kalman"         // 一维卡尔曼滤波
+)
+
+// FilterConfig 单个滤波器的配置
+type FilterConfig struct {
+	Kind             FilterKind
+	Window           int     // 滑动平均、中值和野值剔除的窗口长度
+	Threshold        float64 // 野值剔除阈值，偏离窗口中值超过该值的读数被剔除，单位 g
+	ProcessNoise     float64 // 卡尔曼滤波的过程噪声方差，越大跟踪重量变化越快
+	MeasurementNoise float64 // 卡尔曼滤波的测量噪声方差
+}
+
+// Validate 检查滤波器配置是否合理
+func (c FilterConfig) Validate() error {
+	switch c.Kind {
+	case MovingAverageFilter, MedianFilter:
+		if c.Window < 1 {
+			return fmt.Errorf("%s 滤波窗口至少为 1，实际为 %d", c.Kind, c.Window)
+		}
+	case OutlierFilter:
+		if c.Window < 1 {
+			return fmt.Errorf("%s 滤波窗口至少为 1，实际为 %d", c.Kind, c.Window)
+		}
+		if !(c.Threshold > 0) {
+			return fmt.Errorf("%s 阈值必须为正数，实际为 %v", c.Kind, c.Threshold)
+		}
+	case KalmanFilter:
+		if !(c.ProcessNoise > 0) || !(c.MeasurementNoise > 0) {
+			return fmt.Errorf("%s 噪声方差必须为正数，实际为 %v 和 %v", c.Kind, c.ProcessNoise, c.MeasurementNoise)
+		}
+	default:
+		return fmt.Errorf("未知的滤波器类型 %q", c.Kind)
+	}
+	return nil
+}
+
+// PipelineConfig 各层的滤波流水线配置，未单独配置的层使用 Default
+type PipelineConfig struct {
+	Default []FilterConfig
+	Layers  map[int][]FilterConfig
+}
+
+// Validate 检查所有层的滤波器配置
+func (c PipelineConfig) Validate() error {
+	for i, filter := range c.Default {
+		if err := filter.Validate(); err != nil {
+			return fmt.Errorf("默认滤波器%d: %w", i, err)
+		}
+	}
+	for layer, filters := range c.Layers {
+		for i, filter := range filters {
+			if err := filter.Validate(); err != nil {
+				return fmt.Errorf("第%d层滤波器%d: %w", layer, i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Filter 对读数流逐个滤波
+type Filter interface {
+	Update(value float64) float64 // 输入新读数，返回滤波后的值
+	Reset()                       // 清除历史状态
+}
+
+// NewFilter 根据配置创建滤波器
+func NewFilter(config FilterConfig) (Filter, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	switch config.Kind {
+	case MovingAverageFilter:
+		return &movingAverage{window: config.Window}, nil
+	case MedianFilter:
+		return &median{window: config.Window}, nil
+	case OutlierFilter:
+		return &outlier{window: config.Window, threshold: config.Threshold}, nil
+	default:
+		return &kalman{q: config.ProcessNoise, r: config.MeasurementNoise}, nil
+	}
+}
+
+// movingAverage 滑动平均
+type movingAverage struct {
+	window int
+	values []float64
+	sum    float64
+}
+
+func (f *movingAverage) Update(value float64) float64 {
+	f.values = append(f.values, value)
+	f.sum += value
+	if len(f.values) > f.window {
+		f.sum -= f.values[0]
+		f.values = f.values[1:]
+	}
+	return f.sum / float64(len(f.values))
+}
+
+func (f *movingAverage) Reset() {
+	f.values = nil
+	f.sum = 0
+}
+
+// median 滑动中值，抑制单次尖峰
+type median struct {
+	window int
+	values []float64
+}
+
+func (f *median) Update(value float64) float64 {
+	f.values = append(f.values, value)
+	if len(f.values) > f.window {
+		f.values = f.values[1:]
+	}
+	return medianOf(f.values)
+}
+
+func (f *median) Reset() {
+	f.values = nil
+}
+
+// outlier 野值剔除：偏离最近读数中值超过阈值的读数用中值代替
+// 连续被剔除的读数达到窗口长度时认为重量确实发生变化，接受新的读数
+type outlier struct {
+	window    int
+	threshold float64
+	values    []float64
+	rejected  []float64
+}
+
+func (f *outlier) Update(value float64) float64 {
+	if len(f.values) > 0 && math.Abs(value-medianOf(f.values)) > f.threshold {
+		f.rejected = append(f.rejected, value)
+		if len(f.rejected) < f.window {
+			return medianOf(f.values)
+		}
+		f.values = f.rejected
+		f.rejected = nil
+		return value
+	}
+
+	f.rejected = nil
+	f.values = append(f.values, value)
+	if len(f.values) > f.window {
+		f.values = f.values[1:]
+	}
+	return value
+}
+
+func (f *outlier) Reset() {
+	f.values = nil
+	f.rejected = nil
+}
+
+// kalman 一维卡尔曼滤波，状态为静止重量
+type kalman struct {
+	q, r        float64
+	x, p        float64
+	initialized bool
+}
+
+func (f *kalman) Update(value float64) float64 {
+	if !f.initialized {
+		f.x = value
+		f.p = f.r
+		f.initialized = true
+		return f.x
+	}
+	f.p += f.q
+	k := f.p / (f.p + f.r)
+	f.x += k * (value - f.x)
+	f.p *= 1 - k
+	return f.x
+}
+
+func (f *kalman) Reset() {
+	f.initialized = false
+}
+
+// medianOf 返回中值，不修改输入
+func medianOf(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// Pipeline 依次串联的滤波器
+type Pipeline struct {
+	filters []Filter
+}
+
+// NewPipeline 根据配置创建滤波流水线，配置为空时原样输出读数
+func NewPipeline(configs []FilterConfig) (*Pipeline, error) {
+	pipeline := &Pipeline{}
+	for i, config := range configs {
+		filter, err := NewFilter(config)
+		if err != nil {
+			return nil, fmt.Errorf("滤波器%d: %w", i, err)
+		}
+		pipeline.filters = append(pipeline.filters, filter)
+	}
+	return pipeline, nil
+}
+
+// Update 输入新读数，返回经过所有滤波器后的值
+func (p *Pipeline) Update(value float64) float64 {
+	for _, filter := range p.filters {
+		value = filter.Update(value)
+	}
+	return value
+}
+
+// Reset 清除所有滤波器的历史状态
+func (p *Pipeline) Reset() {
+	for _, filter := range p.filters {
+		filter.Reset()
+	}
+}
+
+// LayerFilters 按层维护滤波流水线，将原始读数流转换为识别使用的稳定值，可被多个 goroutine 并发使用
+type LayerFilters struct {
+	config PipelineConfig
+
+	mu        sync.Mutex
+	pipelines map[int]*Pipeline
+	latest    map[int]float64
+}
+
+// NewLayerFilters 根据配置创建各层的滤波流水线，配置不合理时返回错误
+func NewLayerFilters(config PipelineConfig) (*LayerFilters, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &LayerFilters{
+		config:    config,
+		pipelines: make(map[int]*Pipeline),
+		latest:    make(map[int]float64),
+	}, nil
+}
+
+// Filter 对一个原始读数滤波，返回滤波后的读数
+func (f *LayerFilters) Filter(sample model.Sample) model.Sample {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	pipeline, exists := f.pipelines[sample.Layer]
+	if !exists {
+		configs, ok := f.config.Layers[sample.Layer]
+		if !ok {
+			configs = f.config.Default
+		}
+		// 配置已在创建时校验
+		pipeline, _ = NewPipeline(configs)
+		f.pipelines[sample.Layer] = pipeline
+	}
+
+	value := pipeline.Update(sample.Reading().Float())
+	f.latest[sample.Layer] = value
+	sample.Precise = model.GramsFloat(value)
+	sample.Weight = sample.Precise.RoundGrams()
+	return sample
+}
+
+// FilterAll 依次对一组原始读数滤波
+func (f *LayerFilters) FilterAll(samples []model.Sample) []model.Sample {
+	filtered := make([]model.Sample, len(samples))
+	for i, sample := range samples {
+		filtered[i] = f.Filter(sample)
+	}
+	return filtered
+}
+
+// Snapshot 返回各层当前的稳定值，可直接作为识别的购物前后读数，按层号排序
+func (f *LayerFilters) Snapshot() []model.Layer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	layers := make([]model.Layer, 0, len(f.latest))
+	for index, value := range f.latest {
+		precise := model.GramsFloat(value)
+		layers = append(layers, model.Layer{Index: index, Weight: precise.RoundGrams(), Precise: precise})
+	}
+	sort.Slice(layers, func(i, j int) bool {
+		return layers[i].Index < layers[j].Index
+	})
+	return layers
+}
+
+// Reset 清除某层的滤波状态，例如传感器重新标定之后
+func (f *LayerFilters) Reset(layer int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.pipelines, layer)
+	delete(f.latest, layer)
+}
@@ -0,0 +1,115 @@
+package sensor
+
+import (
+	"VendingMachineWeightRecognition/pkg/model"
+	"math"
+	"testing"
+	"time"
+)
+
+// run 依次输入读数，返回最后的输出
+func run(t *testing.T, config FilterConfig, values ...float64) float64 {
+	filter, err := NewFilter(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := 0.0
+	for _, value := range values {
+		output = filter.Update(value)
+	}
+	return output
+}
+
+// TestFilters 测试各类滤波器的基本行为
+func TestFilters(t *testing.T) {
+	if v := run(t, FilterConfig{Kind: MovingAverageFilter, Window: 3}, 10, 20, 30, 40); v != 30 {
+		t.Errorf("滑动平均应该为30，实际为%v", v)
+	}
+	if v := run(t, FilterConfig{Kind: MedianFilter, Window: 3}, 100, 100, 900); v != 100 {
+		t.Errorf("中值滤波应该抑制尖峰，实际为%v", v)
+	}
+	if v := run(t, FilterConfig{Kind: OutlierFilter, Window: 3, Threshold: 20}, 100, 101, 99, 500); v != 100 {
+		t.Errorf("野值应该被剔除，实际为%v", v)
+	}
+	if v := run(t, FilterConfig{Kind: OutlierFilter, Window: 3, Threshold: 20}, 100, 101, 99, 500, 500, 500); v != 500 {
+		t.Errorf("持续的变化应该被接受，实际为%v", v)
+	}
+
+	// 卡尔曼滤波收敛到真实值附近
+	values := make([]float64, 0)
+	for i := 0; i < 200; i++ {
+		values = append(values, 1000+[]float64{3, -3, 2, -2}[i%4])
+	}
+	if v := run(t, FilterConfig{Kind: KalmanFilter, ProcessNoise: 0.01, MeasurementNoise: 9}, values...); math.Abs(v-1000) > 1 {
+		t.Errorf("卡尔曼滤波应该收敛到1000附近，实际为%v", v)
+	}
+}
+
+// TestFilterConfig_Validate 测试拒绝不合理的滤波器配置
+func TestFilterConfig_Validate(t *testing.T) {
+	invalid := []FilterConfig{
+		{Kind: "lowpass"},
+		{Kind: MovingAverageFilter},
+		{Kind: OutlierFilter, Window: 3},
+		{Kind: KalmanFilter, ProcessNoise: 1},
+	}
+	for _, config := range invalid {
+		if config.Validate() == nil {
+			t.Errorf("配置%+v应该被拒绝", config)
+		}
+	}
+
+	if _, err := NewLayerFilters(PipelineConfig{Layers: map[int][]FilterConfig{2: {{Kind: MedianFilter}}}}); err == nil {
+		t.Error("分层配置也应该被校验")
+	}
+}
+
+// TestLayerFilters 测试按层配置的滤波流水线
+func TestLayerFilters(t *testing.T) {
+	filters, err := NewLayerFilters(PipelineConfig{
+		Default: []FilterConfig{{Kind: MedianFilter, Window: 3}},
+		Layers: map[int][]FilterConfig{
+			2: {
+				{Kind: OutlierFilter, Window: 3, Threshold: 50},
+				{Kind: MovingAverageFilter, Window: 2},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filtered := filters.FilterAll(append(series(1, 5000, 5000, 5800), series(2, 3000, 3002, 3900)...))
+	if filtered[2].Weight != 5000 || filtered[5].Weight != 3002 {
+		t.Errorf("尖峰应该被滤除，实际为%+v", filtered)
+	}
+
+	snapshot := filters.Snapshot()
+	expected := []model.Layer{{Index: 1, Weight: 5000}, {Index: 2, Weight: 3002}}
+	if len(snapshot) != 2 || snapshot[0].Index != expected[0].Index || snapshot[0].Weight != expected[0].Weight ||
+		snapshot[1].Index != expected[1].Index || snapshot[1].Weight != expected[1].Weight {
+		t.Errorf("快照应该为%v，实际为%v", expected, snapshot)
+	}
+
+	filters.Reset(1)
+	if snapshot := filters.Snapshot(); len(snapshot) != 1 {
+		t.Errorf("重置后第1层不应该有稳定值，实际为%v", snapshot)
+	}
+}
+
+// TestLayerFilters_Precise 测试滤波和快照保留克以下的精确读数
+func TestLayerFilters_Precise(t *testing.T) {
+	filters, err := NewLayerFilters(PipelineConfig{Default: []FilterConfig{{Kind: MovingAverageFilter, Window: 2}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filters.FilterAll([]model.Sample{
+		{Layer: 1, Precise: model.GramsFloat(100.2), Time: start},
+		{Layer: 1, Precise: model.GramsFloat(100.4), Time: start.Add(100 * time.Millisecond)},
+	})
+	snapshot := filters.Snapshot()
+	if len(snapshot) != 1 || snapshot[0].Precise != model.GramsFloat(100.3) || snapshot[0].Weight != 100 {
+		t.Errorf("快照应该保留精确读数100.3g，实际为%+v", snapshot)
+	}
+}
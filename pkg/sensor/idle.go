@@ -0,0 +1,115 @@
+package sensor
+
+import (
+	"VendingMachineWeightRecognition/pkg/model"
+	"math"
+	"time"
+)
+
+// MaxWeight 传感器量程上限，单位 g，识别器和健康监测共用
+const MaxWeight = 32767
+
+// IdleStats 单路传感器空闲读数的统计，识别器的噪声估计和健康监测共用
+// 读数按空闲窗口分段，窗口之间可能发生了购物或补货，重量的跳变不计入统计：
+// 噪声只取同一窗口内相邻读数之差，漂移为各窗口内读数对时间回归的合并斜率
+type IdleStats struct {
+	readings int
+	inWindow bool // 当前窗口是否已有读数
+	last     model.Weight
+
+	// 窗口内相邻读数之差的平方和，单位 g²
+	diffs  int
+	square float64
+
+	// 当前窗口内读数（g）对时间（小时，从窗口开始计）的累加和
+	start                       time.Time
+	n, sumT, sumW, sumTT, sumTW float64
+	// 已结束窗口的中心化平方和与交叉和
+	sxx, sxy float64
+
+	stuckSince time.Time // 读数开始保持不变的时间
+	moved      bool      // 窗口之间的会话中该传感器的重量发生过变化
+}
+
+// Observe 记录当前空闲窗口内的一次读数，读数需要按时间顺序输入
+func (s *IdleStats) Observe(weight model.Weight, at time.Time) {
+	if !s.inWindow {
+		s.closeWindow()
+		s.inWindow = true
+		s.start = at
+		if s.readings == 0 || s.moved || weight != s.last {
+			s.stuckSince = at
+		}
+		s.moved = false
+	} else {
+		d := (weight - s.last).Float()
+		s.diffs++
+		s.square += d * d
+		if weight != s.last {
+			s.stuckSince = at
+		}
+	}
+
+	t := at.Sub(s.start).Hours()
+	w := weight.Float()
+	s.readings++
+	s.n++
+	s.sumT += t
+	s.sumW += w
+	s.sumTT += t * t
+	s.sumTW += t * w
+	s.last = weight
+}
+
+// Break 结束当前空闲窗口，之后的读数属于新的窗口
+// 购物、补货等会改变重量的事件之后调用；moved 表示事件中该传感器的重量发生过变化，读数不再视为卡死
+func (s *IdleStats) Break(moved bool) {
+	s.inWindow = false
+	if moved {
+		s.moved = true
+	}
+}
+
+// Readings 返回空闲读数的个数
+func (s *IdleStats) Readings() int {
+	return s.readings
+}
+
+// Noise 返回噪声标准差及参与估计的读数差个数
+func (s *IdleStats) Noise() (model.Weight, int) {
+	if s.diffs == 0 {
+		return 0, 0
+	}
+	// 相邻读数之差的方差是单次读数噪声方差的两倍
+	return model.GramsFloat(math.Sqrt(s.square / float64(s.diffs) / 2)), s.diffs
+}
+
+// Drift 返回漂移速度，单位 g/h，窗口内的时间跨度都为 0 时 ok 为 false
+func (s *IdleStats) Drift() (rate float64, ok bool) {
+	sxx, sxy := s.sxx, s.sxy
+	if s.n > 0 {
+		sxx += s.sumTT - s.sumT*s.sumT/s.n
+		sxy += s.sumTW - s.sumT*s.sumW/s.n
+	}
+	if sxx <= 0 {
+		return 0, false
+	}
+	return sxy / sxx, true
+}
+
+// Stuck 返回截至 now 读数保持完全不变的时长，读数不足两次时为 0
+func (s *IdleStats) Stuck(now time.Time) time.Duration {
+	if s.readings < 2 || !now.After(s.stuckSince) {
+		return 0
+	}
+	return now.Sub(s.stuckSince)
+}
+
+// closeWindow 将当前窗口的回归累加和并入已结束窗口
+func (s *IdleStats) closeWindow() {
+	if s.n > 0 {
+		s.sxx += s.sumTT - s.sumT*s.sumT/s.n
+		s.sxy += s.sumTW - s.sumT*s.sumW/s.n
+	}
+	s.n, s.sumT, s.sumW, s.sumTT, s.sumTW = 0, 0, 0, 0, 0
+}
@@ -0,0 +1,69 @@
+package sensor
+
+import (
+	"VendingMachineWeightRecognition/pkg/model"
+	"sync"
+)
+
+// NoiseEstimator 根据空闲读数按层估计传感器噪声，可被多个 goroutine 并发使用
+// 使用同一空闲窗口内相邻读数之差估计噪声，不受缓慢零点漂移和窗口之间购物、补货的影响
+type NoiseEstimator struct {
+	mu     sync.Mutex
+	layers map[int]*IdleStats
+}
+
+// NewNoiseEstimator 创建噪声估计
+func NewNoiseEstimator() *NoiseEstimator {
+	return &NoiseEstimator{layers: make(map[int]*IdleStats)}
+}
+
+// Observe 记录无人购物时的读数，同一层的读数需要按时间顺序输入
+// 每次调用为一个空闲窗口，只统计同一次调用内相邻读数之差；两次调用之间可能发生了购物或补货，重量的跳变不计入噪声
+func (e *NoiseEstimator) Observe(samples ...model.Sample) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, stats := range e.layers {
+		stats.Break(false)
+	}
+	for _, sample := range samples {
+		stats, exists := e.layers[sample.Layer]
+		if !exists {
+			stats = &IdleStats{}
+			e.layers[sample.Layer] = stats
+		}
+		stats.Observe(sample.Reading(), sample.Time)
+	}
+}
+
+// Sigma 返回某层的噪声标准差及参与估计的读数差个数
+func (e *NoiseEstimator) Sigma(layer int) (model.Weight, int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	stats, exists := e.layers[layer]
+	if !exists {
+		return 0, 0
+	}
+	return stats.Noise()
+}
+
+// Layers 返回已有读数的层
+func (e *NoiseEstimator) Layers() []int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	layers := make([]int, 0, len(e.layers))
+	for layer := range e.layers {
+		layers = append(layers, layer)
+	}
+	return layers
+}
+
+// Reset 清除某层的统计，例如传感器重新标定之后
+func (e *NoiseEstimator) Reset(layer int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	delete(e.layers, layer)
+}
@@ -0,0 +1,62 @@
+package sensor
+
+import (
+	"VendingMachineWeightRecognition/pkg/model"
+	"math"
+	"testing"
+	"time"
+)
+
+// TestNoiseEstimator 测试按层估计噪声且不受漂移影响
+func TestNoiseEstimator(t *testing.T) {
+	estimator := NewNoiseEstimator()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// 第1层噪声 ±1g，第2层在噪声之外缓慢漂移
+	samples := make([]model.Sample, 0, 2000)
+	for i := 0; i < 1000; i++ {
+		noise := []float64{1, -1}[i%2]
+		at := start.Add(time.Duration(i) * time.Second)
+		samples = append(samples,
+			model.Sample{Layer: 1, Precise: model.GramsFloat(5000 + noise), Time: at},
+			model.Sample{Layer: 2, Precise: model.GramsFloat(3000 + noise + float64(i)*0.01), Time: at},
+		)
+	}
+	estimator.Observe(samples...)
+
+	for _, layer := range []int{1, 2} {
+		sigma, n := estimator.Sigma(layer)
+		if n != 999 || math.Abs(sigma.Float()-math.Sqrt2) > 0.05 {
+			t.Errorf("第%d层噪声应该约为1.41g，实际为%v（%d个读数差）", layer, sigma, n)
+		}
+	}
+
+	estimator.Reset(1)
+	if _, n := estimator.Sigma(1); n != 0 {
+		t.Error("重置后不应该有统计")
+	}
+}
+
+// TestNoiseEstimator_PurchaseBetweenWindows 测试两个空闲窗口之间的购物不计入噪声
+func TestNoiseEstimator_PurchaseBetweenWindows(t *testing.T) {
+	estimator := NewNoiseEstimator()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// 空闲读数在 1000g 附近波动 ±0.5g，购物后在 900g 附近波动
+	for window, base := range []float64{1000, 900} {
+		samples := make([]model.Sample, 50)
+		for i := range samples {
+			samples[i] = model.Sample{
+				Layer:   1,
+				Precise: model.GramsFloat(base + []float64{0.5, -0.5}[i%2]),
+				Time:    start.Add(time.Duration(window*100+i) * time.Second),
+			}
+		}
+		estimator.Observe(samples...)
+	}
+
+	sigma, n := estimator.Sigma(1)
+	if n != 98 || math.Abs(sigma.Float()-math.Sqrt2/2) > 0.01 {
+		t.Errorf("噪声应该约为0.71g，实际为%v（%d个读数差）", sigma, n)
+	}
+}
@@ -0,0 +1,225 @@
+package tuning
+
+import (
+	"VendingMachineWeightRecognition/pkg/model"
+	"VendingMachineWeightRecognition/pkg/recognition"
+	"fmt"
+	"sort"
+)
+
+// Purchase 一次会话中某层真实购买的商品
+type Purchase struct {
+	Layer   int
+	GoodsID string
+	Num     int
+}
+
+// Session 一条标注过的购物会话
+type Session struct {
+	BeginLayers []model.Layer
+	EndLayers   []model.Layer
+	Purchases   []Purchase // 真实购买，空表示没有购物
+}
+
+// Dataset 标注数据集
+type Dataset struct {
+	Goods    []model.Goods
+	Stocks   []model.Stock
+	Sessions []Session
+}
+
+// Grid 容差参数的搜索网格
+type Grid struct {
+	SensorTolerances  []int     // 传感器容差，单位 g
+	PackageTolerances []float64 // 包装容差，百分比
+}
+
+// Metrics 某组容差参数下的评估结果
+type Metrics struct {
+	SensorTolerance  int
+	PackageTolerance float64
+	Layer            int // 层号，0 表示整机
+
+	Sessions        int     // 参与评估的会话数
+	Accuracy        float64 // 识别结果与真实购买完全一致且无异常的比例
+	FalseChargeRate float64 // 某商品识别数量多于真实购买数量的比例
+	ExceptionRate   float64 // 产生异常的比例
+}
+
+// outcome 单个会话或单层的评估计数
+type outcome struct {
+	sessions    int
+	correct     int
+	falseCharge int
+	exceptions  int
+}
+
+// add 记录一次评估
+func (o *outcome) add(recognized map[string]int, truth map[string]int, hasException bool) {
+	o.sessions++
+	if hasException {
+		o.exceptions++
+	}
+	if !hasException && sameItems(recognized, truth) {
+		o.correct++
+	}
+	for goodsID, num := range recognized {
+		if num > truth[goodsID] {
+			o.falseCharge++
+			break
+		}
+	}
+}
+
+// metrics 将计数转换为比例
+func (o *outcome) metrics(sensorTolerance int, packageTolerance float64, layer int) Metrics {
+	m := Metrics{
+		SensorTolerance:  sensorTolerance,
+		PackageTolerance: packageTolerance,
+		Layer:            layer,
+		Sessions:         o.sessions,
+	}
+	if o.sessions > 0 {
+		m.Accuracy = float64(o.correct) / float64(o.sessions)
+		m.FalseChargeRate = float64(o.falseCharge) / float64(o.sessions)
+		m.ExceptionRate = float64(o.exceptions) / float64(o.sessions)
+	}
+	return m
+}
+
+// Sweep 在网格上评估每组容差参数，返回整机指标和各层指标
+// 各层指标只统计该层的识别结果和异常，按层号、传感器容差、包装容差排序
+// 网格中有不合理的容差时返回错误
+func Sweep(dataset Dataset, grid Grid) (global []Metrics, layers []Metrics, err error) {
+	global = make([]Metrics, 0)
+	layers = make([]Metrics, 0)
+
+	for _, sensorTolerance := range grid.SensorTolerances {
+		for _, packageTolerance := range grid.PackageTolerances {
+			recognizer, err := recognition.NewWeightRecognizerWithOptions(recognition.Options{
+				SensorTolerance:  sensorTolerance,
+				PackageTolerance: recognition.PercentTolerance(packageTolerance),
+				Goods:            dataset.Goods,
+				Stocks:           dataset.Stocks,
+			})
+			if err != nil {
+				return nil, nil, fmt.Errorf("传感器容差 %dg、包装容差 %v%%: %w", sensorTolerance, packageTolerance, err)
+			}
+
+			total := &outcome{}
+			perLayer := make(map[int]*outcome)
+
+			for _, session := range dataset.Sessions {
+				result := recognizer.Recognize(session.BeginLayers, session.EndLayers)
+
+				// 整机：合并后的商品与真实购买比较
+				recognized := make(map[string]int)
+				for _, item := range result.Items {
+					recognized[item.GoodsID] += item.Num
+				}
+				truth := make(map[string]int)
+				for _, purchase := range session.Purchases {
+					truth[purchase.GoodsID] += purchase.Num
+				}
+				total.add(recognized, truth, len(result.Exceptions) > 0)
+
+				// 各层：分别比较该层的识别结果
+				layerRecognized := make(map[int]map[string]int)
+				for _, layer := range result.Layers {
+					layerRecognized[layer.Layer] = make(map[string]int)
+					for _, item := range layer.Items {
+						layerRecognized[layer.Layer][item.GoodsID] += item.Num
+					}
+				}
+				layerTruth := make(map[int]map[string]int)
+				for _, purchase := range session.Purchases {
+					if _, exists := layerTruth[purchase.Layer]; !exists {
+						layerTruth[purchase.Layer] = make(map[string]int)
+					}
+					layerTruth[purchase.Layer][purchase.GoodsID] += purchase.Num
+				}
+				layerException := make(map[int]bool)
+				for _, e := range result.Exceptions {
+					layerException[e.Layer] = true
+				}
+
+				for _, layer := range session.BeginLayers {
+					if _, exists := perLayer[layer.Index]; !exists {
+						perLayer[layer.Index] = &outcome{}
+					}
+					perLayer[layer.Index].add(layerRecognized[layer.Index], layerTruth[layer.Index], layerException[layer.Index])
+				}
+			}
+
+			global = append(global, total.metrics(sensorTolerance, packageTolerance, 0))
+			for layer, o := range perLayer {
+				layers = append(layers, o.metrics(sensorTolerance, packageTolerance, layer))
+			}
+		}
+	}
+
+	sort.SliceStable(layers, func(i, j int) bool {
+		if layers[i].Layer != layers[j].Layer {
+			return layers[i].Layer < layers[j].Layer
+		}
+		if layers[i].SensorTolerance != layers[j].SensorTolerance {
+			return layers[i].SensorTolerance < layers[j].SensorTolerance
+		}
+		return layers[i].PackageTolerance < layers[j].PackageTolerance
+	})
+
+	return global, layers, nil
+}
+
+// Best 从评估结果中选出最优参数：误收费率最低，其次准确率最高，再次异常率最低
+func Best(metrics []Metrics) (Metrics, bool) {
+	if len(metrics) == 0 {
+		return Metrics{}, false
+	}
+
+	best := metrics[0]
+	for _, m := range metrics[1:] {
+		switch {
+		case m.FalseChargeRate != best.FalseChargeRate:
+			if m.FalseChargeRate < best.FalseChargeRate {
+				best = m
+			}
+		case m.Accuracy != best.Accuracy:
+			if m.Accuracy > best.Accuracy {
+				best = m
+			}
+		case m.ExceptionRate < best.ExceptionRate:
+			best = m
+		}
+	}
+	return best, true
+}
+
+// BestPerLayer 为每一层选出最优参数
+func BestPerLayer(layers []Metrics) map[int]Metrics {
+	grouped := make(map[int][]Metrics)
+	for _, m := range layers {
+		grouped[m.Layer] = append(grouped[m.Layer], m)
+	}
+
+	best := make(map[int]Metrics, len(grouped))
+	for layer, metrics := range grouped {
+		best[layer], _ = Best(metrics)
+	}
+	return best
+}
+
+// sameItems 判断两个商品数量映射是否相同，数量为 0 的项视为不存在
+func sameItems(a, b map[string]int) bool {
+	for goodsID, num := range a {
+		if num != b[goodsID] {
+			return false
+		}
+	}
+	for goodsID, num := range b {
+		if num != a[goodsID] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,109 @@
+package tuning
+
+import (
+	"VendingMachineWeightRecognition/pkg/model"
+	"testing"
+)
+
+// testDataset 第1层商品重量有±6g的误差，第2层读数准确
+func testDataset() Dataset {
+	goods := []model.Goods{
+		{ID: "000001", Weight: 100},
+		{ID: "000002", Weight: 300},
+	}
+
+	stocks := []model.Stock{
+		{GoodsID: "000001", Layer: 1, Num: 10},
+		{GoodsID: "000002", Layer: 2, Num: 10},
+	}
+
+	sessions := make([]Session, 0)
+	for _, noise := range []int{-6, -3, 0, 3, 6} {
+		sessions = append(sessions, Session{
+			BeginLayers: []model.Layer{{Index: 1, Weight: 1000}, {Index: 2, Weight: 3000}},
+			EndLayers:   []model.Layer{{Index: 1, Weight: 900 + noise}, {Index: 2, Weight: 2700}},
+			Purchases: []Purchase{
+				{Layer: 1, GoodsID: "000001", Num: 1},
+				{Layer: 2, GoodsID: "000002", Num: 1},
+			},
+		})
+	}
+
+	return Dataset{Goods: goods, Stocks: stocks, Sessions: sessions}
+}
+
+// TestSweep 测试容差参数扫描
+func TestSweep(t *testing.T) {
+	grid := Grid{
+		SensorTolerances:  []int{2, 10},
+		PackageTolerances: []float64{0},
+	}
+
+	global, layers, err := Sweep(testDataset(), grid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(global) != 2 {
+		t.Fatalf("应该有2组整机指标，实际有%d组", len(global))
+	}
+	if global[0].Accuracy != 0.2 || global[0].ExceptionRate != 0.8 {
+		t.Errorf("2g容差下只有1个会话正确，实际指标%+v", global[0])
+	}
+	if global[1].Accuracy != 1 || global[1].ExceptionRate != 0 {
+		t.Errorf("10g容差下所有会话应该正确，实际指标%+v", global[1])
+	}
+
+	if len(layers) != 4 || layers[0].Layer != 1 || layers[2].Layer != 2 {
+		t.Fatalf("应该按层排序输出4组分层指标，实际为%+v", layers)
+	}
+	if layers[2].Accuracy != 1 || layers[3].Accuracy != 1 {
+		t.Errorf("第2层读数准确，两组参数都应该正确，实际为%+v", layers[2:])
+	}
+
+	best, ok := Best(global)
+	if !ok || best.SensorTolerance != 10 {
+		t.Errorf("最优传感器容差应该是10g，实际为%+v", best)
+	}
+
+	perLayer := BestPerLayer(layers)
+	if perLayer[1].SensorTolerance != 10 {
+		t.Errorf("第1层最优传感器容差应该是10g，实际为%+v", perLayer[1])
+	}
+}
+
+// TestSweep_FalseCharge 测试误收费统计
+func TestSweep_FalseCharge(t *testing.T) {
+	dataset := Dataset{
+		Goods:  []model.Goods{{ID: "000001", Weight: 100}},
+		Stocks: []model.Stock{{GoodsID: "000001", Layer: 1, Num: 10}},
+		Sessions: []Session{
+			{
+				// 只拿了1件，但传感器漂移了 90g
+				BeginLayers: []model.Layer{{Index: 1, Weight: 1000}},
+				EndLayers:   []model.Layer{{Index: 1, Weight: 810}},
+				Purchases:   []Purchase{{Layer: 1, GoodsID: "000001", Num: 1}},
+			},
+		},
+	}
+
+	global, _, err := Sweep(dataset, Grid{SensorTolerances: []int{10}, PackageTolerances: []float64{5}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if global[0].FalseChargeRate != 1 {
+		t.Errorf("多识别1件应该计为误收费，实际指标%+v", global[0])
+	}
+}
+
+// TestSweep_InvalidGrid 测试拒绝不合理的容差网格
+func TestSweep_InvalidGrid(t *testing.T) {
+	for _, grid := range []Grid{
+		{SensorTolerances: []int{-5}, PackageTolerances: []float64{0}},
+		{SensorTolerances: []int{5}, PackageTolerances: []float64{150}},
+	} {
+		if _, _, err := Sweep(testDataset(), grid); err == nil {
+			t.Errorf("网格%+v应该被拒绝", grid)
+		}
+	}
+}